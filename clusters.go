@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/curator4/gator/internal/database"
+	"github.com/curator4/gator/internal/dedup"
+	"github.com/google/uuid"
+)
+
+// consts
+
+// new posts are compared with the posts of other feeds published this
+// long before
+const clusterWindow = 72 * time.Hour
+
+// the feeds of a round are scraped at the same time, they take turns
+// clustering and inserting their posts so each sees the posts of the others
+var clusterMu sync.Mutex
+
+// structs
+type clusterCandidate struct {
+	story   dedup.Story
+	cluster uuid.UUID
+}
+
+// timelineEntry is a post with the same story from other feeds
+type timelineEntry struct {
+	post   database.GetUserPostsRow
+	alsoIn []database.GetUserPostsRow
+}
+
+// helpers
+
+// clusterCandidates loads the recent posts of all other feeds, for new
+// posts of feedID to join their clusters
+func clusterCandidates(s *state, feedID uuid.UUID) ([]clusterCandidate, error) {
+	params := database.GetClusterCandidatesParams{
+		FeedID:      feedID,
+		PublishedAt: time.Now().Add(-clusterWindow),
+	}
+	posts, err := s.db.GetClusterCandidates(context.Background(), params)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]clusterCandidate, 0, len(posts))
+	for _, post := range posts {
+		candidates = append(candidates, clusterCandidate{
+			story:   dedup.NewStory(post.Url, post.Title),
+			cluster: post.ClusterID,
+		})
+	}
+	return candidates, nil
+}
+
+// findCluster returns the cluster of the first candidate that is the same
+// story, or id when there is none and the post starts its own
+func findCluster(candidates []clusterCandidate, story dedup.Story, id uuid.UUID) uuid.UUID {
+	for _, candidate := range candidates {
+		if dedup.Same(candidate.story, story) {
+			return candidate.cluster
+		}
+	}
+	return id
+}
+
+// loadTimeline reads up to limit entries of a user's posts, newest first,
+// with the posts of a cluster collapsed into its newest one. The other
+// posts of a shown cluster end up in alsoIn, however far back they are.
+func loadTimeline(s *state, params database.GetUserPostsParams, limit int) ([]timelineEntry, error) {
+	page := max(limit*2, 50)
+	params.Limit = int32(page)
+
+	var entries []timelineEntry
+	by_cluster := make(map[uuid.UUID]int)
+	for offset := 0; len(entries) < limit; offset += page {
+		params.Offset = int32(offset)
+		posts, err := s.db.GetUserPosts(context.Background(), params)
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range posts {
+			if _, ok := by_cluster[post.ClusterID]; ok {
+				continue
+			}
+			if len(entries) == limit {
+				break
+			}
+			by_cluster[post.ClusterID] = len(entries)
+			entries = append(entries, timelineEntry{post: post})
+		}
+		if len(posts) < page {
+			break
+		}
+	}
+	if len(entries) == 0 {
+		return entries, nil
+	}
+
+	params.ClusterIds = make([]uuid.UUID, 0, len(by_cluster))
+	for cluster := range by_cluster {
+		params.ClusterIds = append(params.ClusterIds, cluster)
+	}
+	for offset := 0; ; offset += page {
+		params.Offset = int32(offset)
+		posts, err := s.db.GetUserPosts(context.Background(), params)
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range posts {
+			i := by_cluster[post.ClusterID]
+			if post.ID != entries[i].post.ID {
+				entries[i].alsoIn = append(entries[i].alsoIn, post)
+			}
+		}
+		if len(posts) < page {
+			return entries, nil
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/curator4/gator/internal/database"
+	"github.com/curator4/gator/internal/digest"
+	"github.com/google/uuid"
+)
+
+// consts
+const digestUsage = "usage: digest [--since 24h] [--format text|markdown|html] [--group feed|category]\n" +
+	"       digest schedule [--every 24h] [--format f] [--group g] email <address>|file <path>\n" +
+	"       digest schedules\n" +
+	"       digest unschedule <id>"
+
+const channelFile = "file"
+
+// what a digest covers, and how often a scheduled one is sent, unless
+// --since or --every say otherwise
+const defaultDigestPeriod = 24 * time.Hour
+
+// a digest lists at most this many posts, newest first
+const maxDigestPosts = 500
+
+// structs
+type digestOptions struct {
+	period  time.Duration
+	format  string
+	groupBy string
+}
+
+type digestScheduleRow struct {
+	ID         uuid.UUID  `json:"id"`
+	ShortID    string     `json:"short_id"`
+	Every      string     `json:"every"`
+	Format     string     `json:"format"`
+	GroupBy    string     `json:"group_by"`
+	Channel    string     `json:"channel"`
+	Target     string     `json:"target"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// handlers
+func handlerDigest(s *state, cmd command, user database.User) error {
+	if len(cmd.args) > 0 {
+		args := cmd.args[1:]
+		switch cmd.args[0] {
+		case "schedule":
+			return digestSchedule(s, args, user)
+		case "schedules":
+			if len(args) != 0 {
+				return errors.New("digest schedules takes no arguments")
+			}
+			return digestSchedules(s, user)
+		case "unschedule":
+			if len(args) != 1 {
+				return errors.New("digest unschedule expects a schedule id")
+			}
+			return digestUnschedule(s, args[0], user)
+		}
+	}
+
+	opts, positional, err := parseDigestOptions(cmd.args, "--since")
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return errors.New(digestUsage)
+	}
+	since := time.Now().Add(-opts.period)
+	params := database.GetUserPostsParams{Since: sql.NullTime{Time: since, Valid: true}}
+	d, err := buildDigest(s, user, params, since, opts.groupBy)
+	if err != nil {
+		return err
+	}
+	return digest.Write(os.Stdout, opts.format, d)
+}
+
+func digestSchedule(s *state, args []string, user database.User) error {
+	opts, positional, err := parseDigestOptions(args, "--every")
+	if err != nil {
+		return err
+	}
+	if opts.period < minDigestInterval {
+		return fmt.Errorf("--every must be at least %v", minDigestInterval)
+	}
+	if len(positional) != 2 {
+		return errors.New(digestUsage)
+	}
+
+	params := database.CreateDigestScheduleParams{
+		ID:           uuid.New(),
+		CreatedAt:    time.Now(),
+		UserID:       user.ID,
+		SendInterval: int32(opts.period / time.Second),
+		Format:       opts.format,
+		GroupBy:      opts.groupBy,
+		Channel:      positional[0],
+	}
+	switch params.Channel {
+	case channelEmail:
+		address, err := mail.ParseAddress(positional[1])
+		if err != nil {
+			return fmt.Errorf("invalid address %s: %w", positional[1], err)
+		}
+		params.Target = address.Address
+	case channelFile:
+		// agg may run from anywhere
+		path := positional[1]
+		if rest, ok := strings.CutPrefix(path, "~/"); ok {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return err
+			}
+			path = filepath.Join(home, rest)
+		}
+		params.Target, err = filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown channel %q, use email or file", params.Channel)
+	}
+
+	schedule, err := s.db.CreateDigestSchedule(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("scheduled digest %s: %s\n", shortID(schedule.ID), describeDigestSchedule(schedule))
+	if schedule.Channel == channelEmail {
+		warnNoSMTP(s)
+	}
+	fmt.Println("`gator agg` sends it")
+	return nil
+}
+
+func digestSchedules(s *state, user database.User) error {
+	stored, err := s.db.GetDigestSchedulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]digestScheduleRow, 0, len(stored))
+	for _, schedule := range stored {
+		row := digestScheduleRow{
+			ID:        schedule.ID,
+			ShortID:   shortID(schedule.ID),
+			Every:     sendInterval(schedule).String(),
+			Format:    schedule.Format,
+			GroupBy:   schedule.GroupBy,
+			Channel:   schedule.Channel,
+			Target:    schedule.Target,
+			CreatedAt: schedule.CreatedAt,
+		}
+		if schedule.LastSentAt.Valid {
+			row.LastSentAt = &schedule.LastSentAt.Time
+		}
+		rows = append(rows, row)
+	}
+
+	return printList(s, rows, listing[digestScheduleRow]{
+		columns: []string{"id", "every", "format", "group_by", "channel", "target", "last_sent_at", "created_at"},
+		row: func(r digestScheduleRow) []string {
+			last_sent := ""
+			if r.LastSentAt != nil {
+				last_sent = r.LastSentAt.Format(time.RFC3339)
+			}
+			return []string{r.ShortID, r.Every, r.Format, r.GroupBy, r.Channel, r.Target, last_sent, r.CreatedAt.Format(time.RFC3339)}
+		},
+		text: func(rows []digestScheduleRow) {
+			if len(rows) == 0 {
+				fmt.Println("no scheduled digests, add one with: gator digest schedule email <address>|file <path>")
+				return
+			}
+			for i, r := range rows {
+				fmt.Printf("%s  %s\n", r.ShortID, describeDigestSchedule(stored[i]))
+			}
+		},
+	})
+}
+
+func digestUnschedule(s *state, ref string, user database.User) error {
+	stored, err := s.db.GetDigestSchedulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	schedule, err := findByIDPrefix(stored, func(schedule database.DigestSchedule) uuid.UUID { return schedule.ID }, ref, "digest schedule", "digest schedules")
+	if err != nil {
+		return err
+	}
+	params := database.DeleteDigestScheduleParams{ID: schedule.ID, UserID: user.ID}
+	if err := s.db.DeleteDigestSchedule(context.Background(), params); err != nil {
+		return err
+	}
+	fmt.Printf("removed digest schedule %s: %s\n", shortID(schedule.ID), describeDigestSchedule(schedule))
+	return nil
+}
+
+// helpers
+
+// parseDigestOptions reads the options digest and digest schedule share,
+// period_flag names the one that sets the period
+func parseDigestOptions(args []string, period_flag string) (digestOptions, []string, error) {
+	opts := digestOptions{
+		period:  defaultDigestPeriod,
+		format:  digest.FormatText,
+		groupBy: digest.GroupFeed,
+	}
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case period_flag, "--format", "--group":
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s expects a value", arg)
+			}
+			i++
+			value := args[i]
+			switch arg {
+			case period_flag:
+				period, err := time.ParseDuration(value)
+				if err != nil || period <= 0 {
+					return opts, nil, fmt.Errorf("%s expects a duration, e.g. 24h", arg)
+				}
+				opts.period = period
+			case "--format":
+				if !slices.Contains(digest.Formats, value) {
+					return opts, nil, fmt.Errorf("--format expects %s", strings.Join(digest.Formats, ", "))
+				}
+				opts.format = value
+			case "--group":
+				if !slices.Contains(digest.Groups, value) {
+					return opts, nil, fmt.Errorf("--group expects %s", strings.Join(digest.Groups, " or "))
+				}
+				opts.groupBy = value
+			}
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	return opts, positional, nil
+}
+
+// buildDigest collects a user's unread posts that params select, with the
+// posts of a story in several feeds listed once. since starts the period the
+// digest names, params says whether it counts publish or fetch times.
+func buildDigest(s *state, user database.User, params database.GetUserPostsParams, since time.Time, groupBy string) (digest.Digest, error) {
+	params.UserID = user.ID
+	params.UnreadOnly = true
+	entries, err := loadTimeline(s, params, maxDigestPosts)
+	if err != nil {
+		return digest.Digest{}, err
+	}
+
+	posts := make([]digest.Post, 0, len(entries))
+	for _, entry := range entries {
+		post := digest.Post{
+			Title:       entry.post.Title,
+			URL:         entry.post.Url,
+			Feed:        entry.post.FeedName,
+			Description: cmp.Or(entry.post.Description.String, entry.post.Content.String),
+			Categories:  entry.post.Categories,
+			PublishedAt: entry.post.PublishedAt,
+		}
+		for _, other := range entry.alsoIn {
+			if !slices.Contains(post.AlsoIn, other.FeedName) && other.FeedName != post.Feed {
+				post.AlsoIn = append(post.AlsoIn, other.FeedName)
+			}
+		}
+		posts = append(posts, post)
+	}
+	return digest.New(fmt.Sprintf("gator digest for %s", user.Name), since, groupBy, posts)
+}
+
+// sendDigests sends the scheduled digests that are due. A digest covers
+// the posts fetched since the last one was sent, whenever they were
+// published.
+func sendDigests(s *state) error {
+	schedules, err := s.db.GetDueDigestSchedules(context.Background(), time.Now())
+	if err != nil {
+		return err
+	}
+	for _, schedule := range schedules {
+		if err := sendDigest(s, schedule); err != nil {
+			fmt.Printf("Error sending digest %s: %v\n", shortID(schedule.ID), err)
+		}
+	}
+	return nil
+}
+
+// sendDigest delivers one scheduled digest. Empty digests aren't mailed,
+// files are written either way.
+func sendDigest(s *state, schedule database.DigestSchedule) error {
+	user, err := s.db.GetUserByID(context.Background(), schedule.UserID)
+	if err != nil {
+		return err
+	}
+	current_time := time.Now()
+	since := schedule.CreatedAt
+	if schedule.LastSentAt.Valid {
+		since = schedule.LastSentAt.Time
+	}
+
+	added := database.GetUserPostsParams{AddedSince: sql.NullTime{Time: since, Valid: true}}
+	d, err := buildDigest(s, user, added, since, schedule.GroupBy)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	if err := digest.Write(&body, schedule.Format, d); err != nil {
+		return err
+	}
+
+	switch schedule.Channel {
+	case channelEmail:
+		if d.Count > 0 {
+			err = mailer(s).Send(schedule.Target, "gator: "+d.Headline(), digest.ContentType(schedule.Format), body.String())
+		}
+	case channelFile:
+		err = writeDigestFile(schedule.Target, current_time, body.Bytes())
+	default:
+		err = fmt.Errorf("unknown channel %q", schedule.Channel)
+	}
+	if err != nil {
+		return err
+	}
+
+	params := database.SetDigestScheduleSentParams{
+		ID:         schedule.ID,
+		LastSentAt: sql.NullTime{Time: current_time, Valid: true},
+	}
+	return s.db.SetDigestScheduleSent(context.Background(), params)
+}
+
+// writeDigestFile replaces the file at path, a {date} in it becomes the
+// day of the digest so each day gets its own file
+func writeDigestFile(path string, date time.Time, body []byte) error {
+	path = strings.ReplaceAll(path, "{date}", date.Format("2006-01-02"))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, body, 0644)
+}
+
+// describeDigestSchedule says in one line what a schedule sends where,
+// e.g. `html by feed every 24h0m0s → email me@example.com`
+func describeDigestSchedule(schedule database.DigestSchedule) string {
+	return fmt.Sprintf("%s by %s every %v → %s %s", schedule.Format, schedule.GroupBy, sendInterval(schedule), schedule.Channel, schedule.Target)
+}
+
+func sendInterval(schedule database.DigestSchedule) time.Duration {
+	return time.Duration(schedule.SendInterval) * time.Second
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// consts
+const (
+	apiKeyPrefix  = "gator_"
+	sessionPrefix = "gators_"
+)
+
+// errors
+var ErrWrongPassword = errors.New("wrong password")
+
+// functions
+
+// GenerateAPIKey returns a new random key and the hash to store for it. The
+// key itself is only ever shown to the user once.
+func GenerateAPIKey() (key string, hash string, err error) {
+	return newToken(apiKeyPrefix)
+}
+
+// GenerateSessionToken returns a new session token for the config file and
+// the hash to store for it.
+func GenerateSessionToken() (token string, hash string, err error) {
+	return newToken(sessionPrefix)
+}
+
+// HashToken hashes an api key or session token for storage and lookup.
+// Tokens are long and random, so a fast unsalted hash is enough.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashPassword hashes a user chosen password with bcrypt.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword returns ErrWrongPassword if password doesn't match hash.
+func CheckPassword(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrWrongPassword
+	}
+	return err
+}
+
+// helpers
+func newToken(prefix string) (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := prefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
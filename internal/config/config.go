@@ -14,7 +14,58 @@ const configFileName = ".gatorconfig.json"
 // structs
 type Config struct {
 	DbUrl string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name`
+	SessionToken string `json:"session_token,omitempty"`
+
+	// fetcher limits, zero means use the rss package defaults
+	MaxFeedSize  int64 `json:"max_feed_size,omitempty"`
+	MaxRedirects int   `json:"max_redirects,omitempty"`
+
+	// fetcher request settings
+	UserAgent string                     `json:"user_agent,omitempty"`
+	Timeout   string                     `json:"timeout,omitempty"`
+	Proxy     string                     `json:"proxy,omitempty"`
+	Hosts     map[string]RequestOverride `json:"hosts,omitempty"`
+	Feeds     map[string]RequestOverride `json:"feeds,omitempty"`
+
+	// politeness per host
+	MinHostDelay       string `json:"min_host_delay,omitempty"`
+	MaxHostConcurrency int    `json:"max_host_concurrency,omitempty"`
+
+	// where `download` saves podcast episodes, ~/Downloads/gator by default
+	DownloadDir string `json:"download_dir,omitempty"`
+
+	// how browse shows descriptions: preview_length in characters (500 by
+	// default, -1 for everything), preview_links footnotes, osc8 or none
+	PreviewLength int    `json:"preview_length,omitempty"`
+	PreviewLinks  string `json:"preview_links,omitempty"`
+
+	// mail server for email notifications and digests
+	SMTP *SMTPConfig `json:"smtp,omitempty"`
+}
+
+// RequestOverride customizes requests to one host (Config.Hosts, keyed by
+// hostname) or one feed (Config.Feeds, keyed by feed url).
+type RequestOverride struct {
+	UserAgent   string            `json:"user_agent,omitempty"`
+	Timeout     string            `json:"timeout,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	BasicAuth   *BasicAuth        `json:"basic_auth,omitempty"`
+	BearerToken string            `json:"bearer_token,omitempty"`
+}
+
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// SMTPConfig is where mail is sent from. Port is 587 by default, username
+// and password are only needed if the server asks for them.
+type SMTPConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	From     string `json:"from"`
 }
 
 
@@ -38,8 +89,9 @@ func Read() (Config, error) {
 	return cfg, nil
 }
 
-func (c *Config) SetUser(name string) error {
-	c.CurrentUserName = name
+// SetSession stores the token of the logged in session, "" logs out.
+func (c *Config) SetSession(token string) error {
+	c.SessionToken = token
 	return write(*c)
 }
 
@@ -64,5 +116,6 @@ func write(cfg Config) error {
 		return err
 	}
 
-	return os.WriteFile(configPath, jsonConfig, 0666)
+	// the config can hold feed credentials
+	return os.WriteFile(configPath, jsonConfig, 0600)
 }
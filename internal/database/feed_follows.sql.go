@@ -88,7 +88,7 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT users.name as user_name, feeds.name as feed_name
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.feed_id, users.name as user_name, feeds.name as feed_name, feeds.url as feed_url
 FROM feed_follows
 INNER JOIN users ON feed_follows.user_id = users.id
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
@@ -96,8 +96,12 @@ WHERE $1 = feed_follows.user_id
 `
 
 type GetFeedFollowsForUserRow struct {
-	UserName string
-	FeedName string
+	ID        uuid.UUID
+	CreatedAt time.Time
+	FeedID    uuid.UUID
+	UserName  string
+	FeedName  string
+	FeedUrl   string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -109,7 +113,14 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	var items []GetFeedFollowsForUserRow
 	for rows.Next() {
 		var i GetFeedFollowsForUserRow
-		if err := rows.Scan(&i.UserName, &i.FeedName); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.FeedID,
+			&i.UserName,
+			&i.FeedName,
+			&i.FeedUrl,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
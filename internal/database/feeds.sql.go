@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createFeed = `-- name: CreateFeed :one
@@ -23,7 +24,7 @@ VALUES (
   $5,
   $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_full_content
 `
 
 type CreateFeedParams struct {
@@ -53,12 +54,63 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.FetchFullContent,
+	)
+	return i, err
+}
+
+const deferFeedFetch = `-- name: DeferFeedFetch :exec
+UPDATE feeds
+SET next_fetch_at = $1, updated_at = $2
+WHERE id = $3
+`
+
+type DeferFeedFetchParams struct {
+	NextFetchAt sql.NullTime
+	UpdatedAt   time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) DeferFeedFetch(ctx context.Context, arg DeferFeedFetchParams) error {
+	_, err := q.db.ExecContext(ctx, deferFeedFetch, arg.NextFetchAt, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_full_content FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.FetchFullContent,
 	)
 	return i, err
 }
 
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_full_content FROM feeds
 WHERE feeds.url = $1
 `
 
@@ -73,20 +125,51 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.FetchFullContent,
+	)
+	return i, err
+}
+
+const getFeedByURLs = `-- name: GetFeedByURLs :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_full_content FROM feeds
+WHERE feeds.url = ANY($1::text[])
+ORDER BY created_at
+LIMIT 1
+`
+
+func (q *Queries) GetFeedByURLs(ctx context.Context, urls []string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByURLs, pq.Array(urls))
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.FetchFullContent,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT feeds.name, feeds.url, users.name as username
+SELECT feeds.id, feeds.created_at, feeds.name, feeds.url, feeds.user_id, users.name as username, feed_credentials.auth_type
 FROM feeds
 JOIN users ON feeds.user_id = users.id
+LEFT JOIN feed_credentials ON feed_credentials.feed_id = feeds.id
 `
 
 type GetFeedsRow struct {
-	Name     string
-	Url      string
-	Username string
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Name      string
+	Url       string
+	UserID    uuid.UUID
+	Username  string
+	AuthType  sql.NullString
 }
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
@@ -98,7 +181,15 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	var items []GetFeedsRow
 	for rows.Next() {
 		var i GetFeedsRow
-		if err := rows.Scan(&i.Name, &i.Url, &i.Username); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.Username,
+			&i.AuthType,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -112,25 +203,49 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+const getNextFeedsToFetch = `-- name: GetNextFeedsToFetch :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_full_content FROM feeds
+WHERE next_fetch_at IS NULL OR next_fetch_at <= $1::timestamp
 ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1
+LIMIT $2
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
-	)
-	return i, err
+type GetNextFeedsToFetchParams struct {
+	Now      time.Time
+	MaxFeeds int32
+}
+
+func (q *Queries) GetNextFeedsToFetch(ctx context.Context, arg GetNextFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch, arg.Now, arg.MaxFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.NextFetchAt,
+			&i.FetchFullContent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const markFeedFetched = `-- name: MarkFeedFetched :exec
@@ -149,3 +264,37 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams
 	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.LastFetchedAt, arg.UpdatedAt, arg.ID)
 	return err
 }
+
+const setFeedFullContent = `-- name: SetFeedFullContent :exec
+UPDATE feeds
+SET fetch_full_content = $1, updated_at = $2
+WHERE id = $3
+`
+
+type SetFeedFullContentParams struct {
+	FetchFullContent bool
+	UpdatedAt        time.Time
+	ID               uuid.UUID
+}
+
+func (q *Queries) SetFeedFullContent(ctx context.Context, arg SetFeedFullContentParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFullContent, arg.FetchFullContent, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $1, updated_at = $2
+WHERE id = $3
+`
+
+type UpdateFeedURLParams struct {
+	Url       string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.Url, arg.UpdatedAt, arg.ID)
+	return err
+}
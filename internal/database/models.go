@@ -11,14 +11,38 @@ import (
 	"github.com/google/uuid"
 )
 
+type DigestSchedule struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UserID       uuid.UUID
+	SendInterval int32
+	Format       string
+	GroupBy      string
+	Channel      string
+	Target       string
+	LastSentAt   sql.NullTime
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Name             string
+	Url              string
+	UserID           uuid.UUID
+	LastFetchedAt    sql.NullTime
+	NextFetchAt      sql.NullTime
+	FetchFullContent bool
+}
+
+type FeedCredential struct {
+	FeedID     uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	AuthType   string
+	Username   sql.NullString
+	QueryParam sql.NullString
+	Secret     string
 }
 
 type FeedFollow struct {
@@ -29,20 +53,94 @@ type FeedFollow struct {
 	FeedID    uuid.UUID
 }
 
+type Notification struct {
+	WatchID   uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+	SentAt    sql.NullTime
+}
+
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     sql.NullString
+	PublishedAt     time.Time
+	FeedID          uuid.UUID
+	Author          sql.NullString
+	Content         sql.NullString
+	CommentsUrl     sql.NullString
+	DurationSeconds sql.NullInt32
+	ImageUrl        sql.NullString
+	FullContent     sql.NullString
+	ClusterID       uuid.UUID
 }
 
-type User struct {
+type PostCategory struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+type PostEnclosure struct {
+	PostID   uuid.UUID
+	Url      string
+	Length   sql.NullInt64
+	MimeType sql.NullString
+}
+
+type PostState struct {
+	UserID   uuid.UUID
+	PostID   uuid.UUID
+	ReadAt   sql.NullTime
+	SavedAt  sql.NullTime
+	HiddenAt sql.NullTime
+}
+
+type PostTag struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	Name   string
+}
+
+type Rule struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	UserID    uuid.UUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Action    string
+	Tag       sql.NullString
+}
+
+type Session struct {
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UserID    uuid.UUID
+}
+
+type User struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	ApiKeyHash   sql.NullString
+	PasswordHash sql.NullString
+}
+
+type Watch struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UserID         uuid.UUID
+	Field          string
+	MatchType      string
+	Pattern        string
+	Channel        string
+	Target         sql.NullString
+	RateLimit      int32
+	DigestInterval sql.NullInt32
+	LastNotifiedAt sql.NullTime
 }
@@ -11,10 +11,11 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createPost = `-- name: CreatePost :exec
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, content, comments_url, duration_seconds, image_url, cluster_id)
 VALUES (
   $1,
   $2,
@@ -23,19 +24,31 @@ VALUES (
   $5,
   $6,
   $7,
-  $8
+  $8,
+  $9,
+  $10,
+  $11,
+  $12,
+  $13,
+  $14
 )
 `
 
 type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     sql.NullString
+	PublishedAt     time.Time
+	FeedID          uuid.UUID
+	Author          sql.NullString
+	Content         sql.NullString
+	CommentsUrl     sql.NullString
+	DurationSeconds sql.NullInt32
+	ImageUrl        sql.NullString
+	ClusterID       uuid.UUID
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
@@ -48,38 +61,571 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Author,
+		arg.Content,
+		arg.CommentsUrl,
+		arg.DurationSeconds,
+		arg.ImageUrl,
+		arg.ClusterID,
 	)
 	return err
 }
 
-const getUserPosts = `-- name: GetUserPosts :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, feeds.name AS feed_name FROM posts
+const createPostCategory = `-- name: CreatePostCategory :exec
+INSERT INTO post_categories (post_id, name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type CreatePostCategoryParams struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) CreatePostCategory(ctx context.Context, arg CreatePostCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, createPostCategory, arg.PostID, arg.Name)
+	return err
+}
+
+const createPostEnclosure = `-- name: CreatePostEnclosure :exec
+INSERT INTO post_enclosures (post_id, url, length, mime_type)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING
+`
+
+type CreatePostEnclosureParams struct {
+	PostID   uuid.UUID
+	Url      string
+	Length   sql.NullInt64
+	MimeType sql.NullString
+}
+
+func (q *Queries) CreatePostEnclosure(ctx context.Context, arg CreatePostEnclosureParams) error {
+	_, err := q.db.ExecContext(ctx, createPostEnclosure,
+		arg.PostID,
+		arg.Url,
+		arg.Length,
+		arg.MimeType,
+	)
+	return err
+}
+
+const getClusterCandidates = `-- name: GetClusterCandidates :many
+SELECT id, cluster_id, url, title FROM posts
+WHERE feed_id <> $1 AND published_at >= $2
+ORDER BY published_at
+`
+
+type GetClusterCandidatesParams struct {
+	FeedID      uuid.UUID
+	PublishedAt time.Time
+}
+
+type GetClusterCandidatesRow struct {
+	ID        uuid.UUID
+	ClusterID uuid.UUID
+	Url       string
+	Title     string
+}
+
+func (q *Queries) GetClusterCandidates(ctx context.Context, arg GetClusterCandidatesParams) ([]GetClusterCandidatesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClusterCandidates, arg.FeedID, arg.PublishedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetClusterCandidatesRow
+	for rows.Next() {
+		var i GetClusterCandidatesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ClusterID,
+			&i.Url,
+			&i.Title,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostByID = `-- name: GetPostByID :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, content, comments_url, duration_seconds, image_url, full_content, cluster_id FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) GetPostByID(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByID, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Content,
+		&i.CommentsUrl,
+		&i.DurationSeconds,
+		&i.ImageUrl,
+		&i.FullContent,
+		&i.ClusterID,
+	)
+	return i, err
+}
+
+const getPostByURL = `-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, content, comments_url, duration_seconds, image_url, full_content, cluster_id FROM posts
+WHERE url = $1
+`
+
+func (q *Queries) GetPostByURL(ctx context.Context, url string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByURL, url)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Content,
+		&i.CommentsUrl,
+		&i.DurationSeconds,
+		&i.ImageUrl,
+		&i.FullContent,
+		&i.ClusterID,
+	)
+	return i, err
+}
+
+const getPostByURLs = `-- name: GetPostByURLs :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, content, comments_url, duration_seconds, image_url, full_content, cluster_id FROM posts
+WHERE url = ANY($1::text[])
+ORDER BY created_at
+LIMIT 1
+`
+
+func (q *Queries) GetPostByURLs(ctx context.Context, urls []string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByURLs, pq.Array(urls))
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Content,
+		&i.CommentsUrl,
+		&i.DurationSeconds,
+		&i.ImageUrl,
+		&i.FullContent,
+		&i.ClusterID,
+	)
+	return i, err
+}
+
+const getPostEnclosures = `-- name: GetPostEnclosures :many
+SELECT post_id, url, length, mime_type FROM post_enclosures
+WHERE post_id = ANY($1::uuid[])
+ORDER BY post_id, url
+`
+
+func (q *Queries) GetPostEnclosures(ctx context.Context, postIds []uuid.UUID) ([]PostEnclosure, error) {
+	rows, err := q.db.QueryContext(ctx, getPostEnclosures, pq.Array(postIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostEnclosure
+	for rows.Next() {
+		var i PostEnclosure
+		if err := rows.Scan(
+			&i.PostID,
+			&i.Url,
+			&i.Length,
+			&i.MimeType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsByIDPrefix = `-- name: GetPostsByIDPrefix :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.comments_url, posts.duration_seconds, posts.image_url, posts.full_content, posts.cluster_id FROM posts
 INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
-INNER JOIN feeds ON posts.feed_id = feeds.id
 WHERE feed_follows.user_id = $1
+  AND posts.id::text LIKE $2::text || '%'
 ORDER BY posts.published_at DESC
+LIMIT 2
+`
+
+type GetPostsByIDPrefixParams struct {
+	UserID uuid.UUID
+	Prefix string
+}
+
+func (q *Queries) GetPostsByIDPrefix(ctx context.Context, arg GetPostsByIDPrefixParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByIDPrefix, arg.UserID, arg.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Content,
+			&i.CommentsUrl,
+			&i.DurationSeconds,
+			&i.ImageUrl,
+			&i.FullContent,
+			&i.ClusterID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserCategories = `-- name: GetUserCategories :many
+SELECT names.name, count(*) AS posts
+FROM (
+  SELECT post_categories.post_id, post_categories.name
+  FROM post_categories
+  INNER JOIN posts ON post_categories.post_id = posts.id
+  INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+  WHERE feed_follows.user_id = $1
+  UNION
+  SELECT post_tags.post_id, post_tags.name
+  FROM post_tags
+  WHERE post_tags.user_id = $1
+) AS names
+GROUP BY names.name
+ORDER BY count(*) DESC, names.name
 LIMIT $2
 `
 
-type GetUserPostsParams struct {
+type GetUserCategoriesParams struct {
+	UserID uuid.UUID
+	Limit  int32
+}
+
+type GetUserCategoriesRow struct {
+	Name  string
+	Posts int64
+}
+
+func (q *Queries) GetUserCategories(ctx context.Context, arg GetUserCategoriesParams) ([]GetUserCategoriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserCategories, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserCategoriesRow
+	for rows.Next() {
+		var i GetUserCategoriesRow
+		if err := rows.Scan(&i.Name, &i.Posts); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserEpisodes = `-- name: GetUserEpisodes :many
+SELECT posts.id, posts.title, posts.published_at, posts.duration_seconds, posts.image_url,
+  feeds.name AS feed_name,
+  post_enclosures.url AS enclosure_url, post_enclosures.length, post_enclosures.mime_type
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN post_enclosures ON post_enclosures.post_id = posts.id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+  AND post_states.hidden_at IS NULL
+  AND ($2::uuid IS NULL OR posts.feed_id = $2)
+ORDER BY posts.published_at DESC, post_enclosures.url
+LIMIT $3
+`
+
+type GetUserEpisodesParams struct {
 	UserID uuid.UUID
+	FeedID uuid.NullUUID
 	Limit  int32
 }
 
+type GetUserEpisodesRow struct {
+	ID              uuid.UUID
+	Title           string
+	PublishedAt     time.Time
+	DurationSeconds sql.NullInt32
+	ImageUrl        sql.NullString
+	FeedName        string
+	EnclosureUrl    string
+	Length          sql.NullInt64
+	MimeType        sql.NullString
+}
+
+func (q *Queries) GetUserEpisodes(ctx context.Context, arg GetUserEpisodesParams) ([]GetUserEpisodesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserEpisodes, arg.UserID, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserEpisodesRow
+	for rows.Next() {
+		var i GetUserEpisodesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.PublishedAt,
+			&i.DurationSeconds,
+			&i.ImageUrl,
+			&i.FeedName,
+			&i.EnclosureUrl,
+			&i.Length,
+			&i.MimeType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserLatestPostTime = `-- name: GetUserLatestPostTime :one
+SELECT COALESCE(MAX(posts.created_at), 'epoch')::timestamp AS latest
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+`
+
+func (q *Queries) GetUserLatestPostTime(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getUserLatestPostTime, userID)
+	var latest time.Time
+	err := row.Scan(&latest)
+	return latest, err
+}
+
+const getUserPostByID = `-- name: GetUserPostByID :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.comments_url, posts.duration_seconds, posts.image_url, posts.full_content, posts.cluster_id FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+  AND posts.id = $2
+`
+
+type GetUserPostByIDParams struct {
+	UserID uuid.UUID
+	ID     uuid.UUID
+}
+
+func (q *Queries) GetUserPostByID(ctx context.Context, arg GetUserPostByIDParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getUserPostByID, arg.UserID, arg.ID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Content,
+		&i.CommentsUrl,
+		&i.DurationSeconds,
+		&i.ImageUrl,
+		&i.FullContent,
+		&i.ClusterID,
+	)
+	return i, err
+}
+
+const getUserPostByURLs = `-- name: GetUserPostByURLs :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.comments_url, posts.duration_seconds, posts.image_url, posts.full_content, posts.cluster_id FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+  AND posts.url = ANY($2::text[])
+ORDER BY posts.created_at
+LIMIT 1
+`
+
+type GetUserPostByURLsParams struct {
+	UserID uuid.UUID
+	Urls   []string
+}
+
+func (q *Queries) GetUserPostByURLs(ctx context.Context, arg GetUserPostByURLsParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getUserPostByURLs, arg.UserID, pq.Array(arg.Urls))
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Content,
+		&i.CommentsUrl,
+		&i.DurationSeconds,
+		&i.ImageUrl,
+		&i.FullContent,
+		&i.ClusterID,
+	)
+	return i, err
+}
+
+const getUserPosts = `-- name: GetUserPosts :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.comments_url, posts.duration_seconds, posts.image_url, posts.full_content, posts.cluster_id, feeds.name AS feed_name, feeds.url AS feed_url,
+  COALESCE(
+    (SELECT array_agg(names.name ORDER BY names.name) FROM (
+      SELECT post_categories.name FROM post_categories WHERE post_categories.post_id = posts.id
+      UNION
+      SELECT post_tags.name FROM post_tags WHERE post_tags.post_id = posts.id AND post_tags.user_id = feed_follows.user_id
+    ) AS names),
+    '{}'
+  )::text[] AS categories,
+  post_states.read_at, post_states.saved_at
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+  AND post_states.hidden_at IS NULL
+  AND ($2::uuid IS NULL OR posts.feed_id = $2)
+  AND ($3::timestamp IS NULL OR posts.published_at >= $3)
+  AND ($4::timestamp IS NULL OR posts.created_at >= $4)
+  AND ($5::uuid[] IS NULL OR posts.cluster_id = ANY($5::uuid[]))
+  AND ($6::text IS NULL
+    OR posts.title ILIKE '%' || $6 || '%'
+    OR posts.description ILIKE '%' || $6 || '%'
+    OR posts.content ILIKE '%' || $6 || '%'
+    OR posts.full_content ILIKE '%' || $6 || '%')
+  AND ($7::text IS NULL OR EXISTS (
+    SELECT 1 FROM post_categories
+    WHERE post_categories.post_id = posts.id
+      AND lower(post_categories.name) = lower($7)
+  ) OR EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.post_id = posts.id
+      AND post_tags.user_id = feed_follows.user_id
+      AND lower(post_tags.name) = lower($7)
+  ))
+  AND (NOT $8::boolean OR post_states.read_at IS NULL)
+  AND (NOT $9::boolean OR post_states.saved_at IS NOT NULL)
+ORDER BY posts.published_at DESC
+LIMIT $11 OFFSET $10
+`
+
+type GetUserPostsParams struct {
+	UserID     uuid.UUID
+	FeedID     uuid.NullUUID
+	Since      sql.NullTime
+	AddedSince sql.NullTime
+	ClusterIds []uuid.UUID
+	Search     sql.NullString
+	Category   sql.NullString
+	UnreadOnly bool
+	SavedOnly  bool
+	Offset     int32
+	Limit      int32
+}
+
 type GetUserPostsRow struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt time.Time
-	FeedID      uuid.UUID
-	FeedName    string
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     sql.NullString
+	PublishedAt     time.Time
+	FeedID          uuid.UUID
+	Author          sql.NullString
+	Content         sql.NullString
+	CommentsUrl     sql.NullString
+	DurationSeconds sql.NullInt32
+	ImageUrl        sql.NullString
+	FullContent     sql.NullString
+	ClusterID       uuid.UUID
+	FeedName        string
+	FeedUrl         string
+	Categories      []string
+	ReadAt          sql.NullTime
+	SavedAt         sql.NullTime
 }
 
 func (q *Queries) GetUserPosts(ctx context.Context, arg GetUserPostsParams) ([]GetUserPostsRow, error) {
-	rows, err := q.db.QueryContext(ctx, getUserPosts, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getUserPosts,
+		arg.UserID,
+		arg.FeedID,
+		arg.Since,
+		arg.AddedSince,
+		pq.Array(arg.ClusterIds),
+		arg.Search,
+		arg.Category,
+		arg.UnreadOnly,
+		arg.SavedOnly,
+		arg.Offset,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -96,7 +642,18 @@ func (q *Queries) GetUserPosts(ctx context.Context, arg GetUserPostsParams) ([]G
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Author,
+			&i.Content,
+			&i.CommentsUrl,
+			&i.DurationSeconds,
+			&i.ImageUrl,
+			&i.FullContent,
+			&i.ClusterID,
 			&i.FeedName,
+			&i.FeedUrl,
+			pq.Array(&i.Categories),
+			&i.ReadAt,
+			&i.SavedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -110,3 +667,20 @@ func (q *Queries) GetUserPosts(ctx context.Context, arg GetUserPostsParams) ([]G
 	}
 	return items, nil
 }
+
+const setPostFullContent = `-- name: SetPostFullContent :exec
+UPDATE posts
+SET full_content = $1, updated_at = $2
+WHERE id = $3
+`
+
+type SetPostFullContentParams struct {
+	FullContent sql.NullString
+	UpdatedAt   time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) SetPostFullContent(ctx context.Context, arg SetPostFullContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostFullContent, arg.FullContent, arg.UpdatedAt, arg.ID)
+	return err
+}
@@ -0,0 +1,129 @@
+// Package dedup tells whether two posts from different feeds are the same
+// story: either their links lead to the same page once tracking parameters
+// and other noise are removed, or their titles share most of their words.
+package dedup
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/curator4/gator/internal/urlcanon"
+)
+
+// consts
+const (
+	// titles need this many significant words before they are compared,
+	// short ones like "Weekly update" match too easily
+	minTitleWords = 4
+
+	wordThreshold    = 0.6
+	shingleThreshold = 0.5
+)
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "has": true, "have": true, "in": true, "is": true, "it": true, "its": true,
+	"of": true, "on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "was": true,
+	"were": true, "will": true, "with": true, "after": true, "over": true, "new": true, "says": true,
+}
+
+// referralParams say where a click came from on top of the tracking
+// parameters of urlcanon. Too common to strip from stored urls, but they
+// don't make a different story.
+var referralParams = []string{
+	"ref", "ref_src", "ref_url", "cmpid", "ncid", "spm", "share", "smid", "rss", "feature",
+}
+
+// structs
+
+// Story is a post prepared for comparison.
+type Story struct {
+	Key      string // the link without scheme, www, tracking parameters and fragment
+	words    map[string]bool
+	shingles map[string]bool
+}
+
+// functions
+
+// NewStory prepares a post for comparison with others.
+func NewStory(link, title string) Story {
+	words := significantWords(title)
+	story := Story{
+		Key:      URLKey(link),
+		words:    make(map[string]bool, len(words)),
+		shingles: make(map[string]bool, len(words)),
+	}
+	for i, word := range words {
+		story.words[word] = true
+		if i > 0 {
+			story.shingles[words[i-1]+" "+word] = true
+		}
+	}
+	return story
+}
+
+// Same reports whether a and b are most likely the same story.
+func Same(a, b Story) bool {
+	if a.Key != "" && a.Key == b.Key {
+		return true
+	}
+	if len(a.words) < minTitleWords || len(b.words) < minTitleWords {
+		return false
+	}
+	return jaccard(a.words, b.words) >= wordThreshold || jaccard(a.shingles, b.shingles) >= shingleThreshold
+}
+
+// URLKey reduces a link to what identifies the page: on top of the
+// canonical form, http and https, www and bare hosts, referral parameters
+// and the order of the query don't matter. Links that aren't http(s) give
+// an empty key.
+func URLKey(link string) string {
+	canonical, err := urlcanon.Canonicalize(link)
+	if err != nil {
+		return ""
+	}
+	u, err := url.Parse(canonical)
+	if err != nil {
+		return ""
+	}
+
+	query := u.Query()
+	for key := range query {
+		if slices.Contains(referralParams, strings.ToLower(key)) {
+			query.Del(key)
+		}
+	}
+
+	key := strings.TrimPrefix(u.Host, "www.") + strings.TrimRight(u.EscapedPath(), "/")
+	if encoded := query.Encode(); encoded != "" {
+		key += "?" + encoded
+	}
+	return key
+}
+
+// helpers
+
+// significantWords lowercases a title and drops punctuation and stop words
+func significantWords(title string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return slices.DeleteFunc(fields, func(word string) bool {
+		return stopWords[word]
+	})
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,246 @@
+// Package digest renders a summary of unread posts, grouped by feed or by
+// category, as plain text, markdown or html.
+package digest
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/curator4/gator/internal/htmltext"
+)
+
+// consts
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+const (
+	GroupFeed     = "feed"
+	GroupCategory = "category"
+)
+
+var (
+	Formats = []string{FormatText, FormatMarkdown, FormatHTML}
+	Groups  = []string{GroupFeed, GroupCategory}
+)
+
+// posts without categories are listed under this name, after the others
+const uncategorized = "Uncategorized"
+
+// summaries are cut after this many characters
+const summaryLength = 200
+
+// structs
+
+// Post is a post to summarize. Description is html, as stored.
+type Post struct {
+	Title       string
+	URL         string
+	Feed        string
+	Description string
+	Categories  []string
+	AlsoIn      []string // feeds with the same story
+	PublishedAt time.Time
+}
+
+// Digest is the posts of a period, grouped.
+type Digest struct {
+	Title  string
+	Since  time.Time
+	Count  int
+	Groups []Group
+}
+
+type Group struct {
+	Name  string
+	Posts []Entry
+}
+
+// Entry is a post with its summary, as the formats show it.
+type Entry struct {
+	Post
+	Summary string
+}
+
+// functions
+
+// New groups posts by feed or category. Groups are sorted by name, posts
+// keep their order. Posts in several categories are listed in each.
+func New(title string, since time.Time, groupBy string, posts []Post) (Digest, error) {
+	if !slices.Contains(Groups, groupBy) {
+		return Digest{}, fmt.Errorf("unknown grouping %q, use %s", groupBy, strings.Join(Groups, " or "))
+	}
+
+	d := Digest{Title: title, Since: since, Count: len(posts)}
+	by_name := make(map[string]int)
+	add := func(name string, e Entry) {
+		i, ok := by_name[strings.ToLower(name)]
+		if !ok {
+			i = len(d.Groups)
+			by_name[strings.ToLower(name)] = i
+			d.Groups = append(d.Groups, Group{Name: name})
+		}
+		d.Groups[i].Posts = append(d.Groups[i].Posts, e)
+	}
+	for _, post := range posts {
+		e := Entry{Post: post, Summary: summarize(post.Description)}
+		if groupBy == GroupFeed {
+			add(post.Feed, e)
+			continue
+		}
+		if len(post.Categories) == 0 {
+			add(uncategorized, e)
+		}
+		for _, category := range post.Categories {
+			add(strings.TrimSpace(category), e)
+		}
+	}
+
+	slices.SortStableFunc(d.Groups, func(a, b Group) int {
+		if (a.Name == uncategorized) != (b.Name == uncategorized) {
+			if a.Name == uncategorized {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	return d, nil
+}
+
+// ContentType is the media type to mail a format with, markdown reads fine
+// as plain text.
+func ContentType(format string) string {
+	if format == FormatHTML {
+		return "text/html"
+	}
+	return "text/plain"
+}
+
+// Write renders d as format.
+func Write(w io.Writer, format string, d Digest) error {
+	switch format {
+	case FormatText:
+		return writeText(w, d)
+	case FormatMarkdown:
+		return writeMarkdown(w, d)
+	case FormatHTML:
+		return htmlTemplate.Execute(w, d)
+	}
+	return fmt.Errorf("unknown digest format %q, use %s", format, strings.Join(Formats, ", "))
+}
+
+// Headline says how many posts the digest has and since when.
+func (d Digest) Headline() string {
+	since := d.Since.Local().Format("2006-01-02 15:04")
+	switch d.Count {
+	case 0:
+		return "No unread posts since " + since
+	case 1:
+		return "1 unread post since " + since
+	}
+	return fmt.Sprintf("%d unread posts since %s", d.Count, since)
+}
+
+// helpers
+func writeText(w io.Writer, d Digest) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s\n", d.Title, d.Headline())
+	for _, group := range d.Groups {
+		fmt.Fprintf(&b, "\n%s (%d)\n", group.Name, len(group.Posts))
+		for _, post := range group.Posts {
+			fmt.Fprintf(&b, "\n  %s\n", post.Title)
+			if group.Name != post.Feed {
+				fmt.Fprintf(&b, "  %s · %s\n", post.Feed, post.URL)
+			} else {
+				fmt.Fprintf(&b, "  %s\n", post.URL)
+			}
+			if len(post.AlsoIn) > 0 {
+				fmt.Fprintf(&b, "  Also in: %s\n", strings.Join(post.AlsoIn, ", "))
+			}
+			if post.Summary != "" {
+				fmt.Fprintf(&b, "  %s\n", post.Summary)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdown(w io.Writer, d Digest) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n%s\n", escapeMarkdown(d.Title), d.Headline())
+	for _, group := range d.Groups {
+		fmt.Fprintf(&b, "\n## %s (%d)\n\n", escapeMarkdown(group.Name), len(group.Posts))
+		for _, post := range group.Posts {
+			fmt.Fprintf(&b, "- [%s](%s)", escapeMarkdown(post.Title), markdownURL(post.URL))
+			if group.Name != post.Feed {
+				fmt.Fprintf(&b, " · %s", escapeMarkdown(post.Feed))
+			}
+			if len(post.AlsoIn) > 0 {
+				fmt.Fprintf(&b, " · also in %s", escapeMarkdown(strings.Join(post.AlsoIn, ", ")))
+			}
+			b.WriteString("\n")
+			if post.Summary != "" {
+				fmt.Fprintf(&b, "  %s\n", escapeMarkdown(post.Summary))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`,
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// markdownURL keeps a url from ending the link early
+func markdownURL(u string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(u)
+}
+
+// summarize turns a description into one line of text
+func summarize(description string) string {
+	if description == "" {
+		return ""
+	}
+	text := htmltext.Render(description, htmltext.Options{MaxLength: summaryLength, Links: htmltext.LinksNone})
+	return strings.Join(strings.Fields(text), " ")
+}
+
+var htmlTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body style="font-family: sans-serif; max-width: 40em; margin: 0 auto; line-height: 1.4">
+<h1 style="font-size: 1.4em">{{.Title}}</h1>
+<p style="color: #666">{{.Headline}}</p>
+{{- range .Groups}}
+<h2 style="font-size: 1.15em; border-bottom: 1px solid #ddd">{{.Name}} ({{len .Posts}})</h2>
+<ul style="padding-left: 1.2em">
+{{- $group := .Name}}
+{{- range .Posts}}
+<li style="margin-bottom: 0.8em">
+<a href="{{.URL}}">{{.Title}}</a>
+{{- if ne $group .Feed}} <span style="color: #666">· {{.Feed}}</span>{{end}}
+{{- if .AlsoIn}} <span style="color: #666">· also in {{range $i, $feed := .AlsoIn}}{{if $i}}, {{end}}{{$feed}}{{end}}</span>{{end}}
+{{- if .Summary}}<br><span style="color: #333">{{.Summary}}</span>{{end}}
+</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`))
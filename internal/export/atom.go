@@ -0,0 +1,124 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// structs
+type atomDocument struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Subtitle  string      `xml:"subtitle,omitempty"`
+	Updated   string      `xml:"updated"`
+	Generator string      `xml:"generator"`
+	Author    atomPerson  `xml:"author"`
+	Links     []atomLink  `xml:"link"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     *atomPerson    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+	Source     *atomSource    `xml:"source,omitempty"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomSource struct {
+	ID    string     `xml:"id,omitempty"`
+	Title string     `xml:"title"`
+	Links []atomLink `xml:"link"`
+}
+
+// functions
+func writeAtom(w io.Writer, feed Feed) error {
+	doc := atomDocument{
+		ID:        feed.ID,
+		Title:     feed.Title,
+		Subtitle:  feed.Description,
+		Updated:   feed.Updated.Format(time.RFC3339),
+		Generator: generator,
+		Author:    atomPerson{Name: generator},
+	}
+	if feed.Link != "" {
+		doc.Links = append(doc.Links, atomLink{Href: feed.Link, Rel: "alternate"})
+	}
+	if feed.SelfURL != "" {
+		doc.Links = append(doc.Links, atomLink{Href: feed.SelfURL, Rel: "self", Type: "application/atom+xml"})
+	}
+
+	for _, item := range feed.Items {
+		published := item.Published.Format(time.RFC3339)
+		entry := atomEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Links:     []atomLink{{Href: item.URL, Rel: "alternate"}},
+			Published: published,
+			Updated:   published,
+		}
+		for _, enclosure := range item.Enclosures {
+			entry.Links = append(entry.Links, atomLink{
+				Href:   enclosure.URL,
+				Rel:    "enclosure",
+				Type:   enclosure.Type,
+				Length: enclosure.Length,
+			})
+		}
+		if item.CommentsURL != "" {
+			entry.Links = append(entry.Links, atomLink{Href: item.CommentsURL, Rel: "replies", Type: "text/html"})
+		}
+		for _, category := range item.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category})
+		}
+		if item.Description != "" {
+			entry.Summary = &atomText{Type: "html", Value: item.Description}
+		}
+		if item.Content != "" {
+			entry.Content = &atomText{Type: "html", Value: item.Content}
+		}
+		if item.Author != "" {
+			entry.Author = &atomPerson{Name: item.Author}
+		}
+		if item.SourceName != "" {
+			if entry.Author == nil {
+				entry.Author = &atomPerson{Name: item.SourceName}
+			}
+			source := &atomSource{ID: item.SourceURL, Title: item.SourceName}
+			if item.SourceURL != "" {
+				source.Links = []atomLink{{Href: item.SourceURL, Rel: "self"}}
+			}
+			entry.Source = source
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	return writeXML(w, doc)
+}
@@ -0,0 +1,130 @@
+package export
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/curator4/gator/internal/database"
+	"github.com/curator4/gator/internal/rss"
+	"github.com/google/uuid"
+)
+
+// consts
+const (
+	FormatRSS      = "rss"
+	FormatAtom     = "atom"
+	FormatJSONFeed = "jsonfeed"
+)
+
+const generator = "gator"
+
+// structs
+
+// Feed is a timeline to re-publish, independent of the output format.
+type Feed struct {
+	ID          string // stable id, used by atom and as a json feed fallback
+	Title       string
+	Link        string // home page, required for rss
+	SelfURL     string // where the document itself is served, optional
+	Description string
+	Updated     time.Time
+	Items       []Item
+}
+
+type Item struct {
+	ID          string
+	Title       string
+	URL         string
+	Description string // html
+	Content     string // full html content, optional
+	Author      string
+	Categories  []string
+	Enclosures  []Enclosure
+	CommentsURL string
+	Published   time.Time
+	SourceName  string
+	SourceURL   string
+}
+
+type Enclosure struct {
+	URL    string
+	Length int64 // 0 when unknown
+	Type   string
+}
+
+// functions
+
+// PostItems turns GetUserPosts results and their enclosures into feed
+// items. Post ids make the guids, so they stay stable across exports.
+func PostItems(posts []database.GetUserPostsRow, enclosures []database.PostEnclosure) []Item {
+	byPost := make(map[uuid.UUID][]Enclosure)
+	for _, enclosure := range enclosures {
+		byPost[enclosure.PostID] = append(byPost[enclosure.PostID], Enclosure{
+			URL:    enclosure.Url,
+			Length: enclosure.Length.Int64,
+			Type:   enclosure.MimeType.String,
+		})
+	}
+
+	items := make([]Item, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, Item{
+			ID:          "urn:uuid:" + post.ID.String(),
+			Title:       post.Title,
+			URL:         post.Url,
+			Description: post.Description.String,
+			Content:     cmp.Or(post.FullContent.String, post.Content.String),
+			Author:      post.Author.String,
+			Categories:  post.Categories,
+			Enclosures:  byPost[post.ID],
+			CommentsURL: post.CommentsUrl.String,
+			Published:   post.PublishedAt,
+			SourceName:  post.FeedName,
+			SourceURL:   rss.RedactURL(post.FeedUrl),
+		})
+	}
+	return items
+}
+
+// ContentType is the media type to serve a format with.
+func ContentType(format string) string {
+	switch format {
+	case FormatRSS:
+		return "application/rss+xml; charset=utf-8"
+	case FormatAtom:
+		return "application/atom+xml; charset=utf-8"
+	case FormatJSONFeed:
+		return "application/feed+json; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
+
+// Write renders feed as format.
+func Write(w io.Writer, format string, feed Feed) error {
+	switch format {
+	case FormatRSS:
+		return writeRSS(w, feed)
+	case FormatAtom:
+		return writeAtom(w, feed)
+	case FormatJSONFeed:
+		return writeJSONFeed(w, feed)
+	}
+	return fmt.Errorf("unknown feed format %q, use rss, atom or jsonfeed", format)
+}
+
+// helpers
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
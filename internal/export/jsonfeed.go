@@ -0,0 +1,102 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// structs
+
+// jsonFeed follows https://www.jsonfeed.org/version/1.1/
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url,omitempty"`
+	Title         string           `json:"title,omitempty"`
+	ContentHTML   string           `json:"content_html"`
+	Summary       string           `json:"summary,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+	Attachments   []jsonAttachment `json:"attachments,omitempty"`
+	Source        *jsonFeedSource  `json:"_source,omitempty"`
+}
+
+type jsonAttachment struct {
+	URL         string `json:"url"`
+	MimeType    string `json:"mime_type"`
+	SizeInBytes int64  `json:"size_in_bytes,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// jsonFeedSource is an extension, json feed has no source attribution
+type jsonFeedSource struct {
+	Title   string `json:"title"`
+	FeedURL string `json:"feed_url,omitempty"`
+}
+
+// functions
+func writeJSONFeed(w io.Writer, feed Feed) error {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feed.Title,
+		HomePageURL: feed.Link,
+		FeedURL:     feed.SelfURL,
+		Description: feed.Description,
+		Items:       []jsonFeedItem{},
+	}
+
+	for _, item := range feed.Items {
+		ji := jsonFeedItem{
+			ID:            item.ID,
+			URL:           item.URL,
+			Title:         item.Title,
+			ContentHTML:   item.Description,
+			DatePublished: item.Published.Format(time.RFC3339),
+		}
+		// the description becomes the summary when there is full content
+		if item.Content != "" {
+			ji.ContentHTML = item.Content
+			ji.Summary = item.Description
+		}
+		for _, enclosure := range item.Enclosures {
+			mimeType := enclosure.Type
+			if mimeType == "" {
+				mimeType = "application/octet-stream"
+			}
+			ji.Attachments = append(ji.Attachments, jsonAttachment{
+				URL:         enclosure.URL,
+				MimeType:    mimeType,
+				SizeInBytes: enclosure.Length,
+			})
+		}
+		ji.Tags = item.Categories
+		if item.Author != "" {
+			ji.Authors = []jsonFeedAuthor{{Name: item.Author}}
+		}
+		if item.SourceName != "" {
+			if ji.Authors == nil {
+				ji.Authors = []jsonFeedAuthor{{Name: item.SourceName}}
+			}
+			ji.Source = &jsonFeedSource{Title: item.SourceName, FeedURL: item.SourceURL}
+		}
+		doc.Items = append(doc.Items, ji)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(doc)
+}
@@ -0,0 +1,111 @@
+package export
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"time"
+)
+
+// structs
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Content string     `xml:"xmlns:content,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	AtomLink      *atomLink `xml:"atom:link,omitempty"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Generator     string    `xml:"generator"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string         `xml:"title"`
+	Link        string         `xml:"link"`
+	GUID        rssGUID        `xml:"guid"`
+	PubDate     string         `xml:"pubDate"`
+	Creator     string         `xml:"dc:creator,omitempty"`
+	Categories  []string       `xml:"category"`
+	Description string         `xml:"description,omitempty"`
+	Content     string         `xml:"content:encoded,omitempty"`
+	Comments    string         `xml:"comments,omitempty"`
+	Enclosures  []rssEnclosure `xml:"enclosure"`
+	Source      *rssSource     `xml:"source,omitempty"`
+}
+
+// rssEnclosure always has a length, 0 means unknown
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssSource struct {
+	URL  string `xml:"url,attr"`
+	Name string `xml:",chardata"`
+}
+
+// functions
+func writeRSS(w io.Writer, feed Feed) error {
+	// rss 2.0 has no channel without a link
+	if feed.Link == "" {
+		return errors.New("rss needs a channel link")
+	}
+	channel := rssChannel{
+		Title:         feed.Title,
+		Link:          feed.Link,
+		Description:   feed.Description,
+		LastBuildDate: feed.Updated.Format(time.RFC1123Z),
+		Generator:     generator,
+	}
+	if feed.SelfURL != "" {
+		channel.AtomLink = &atomLink{Href: feed.SelfURL, Rel: "self", Type: "application/rss+xml"}
+	}
+
+	for _, item := range feed.Items {
+		ri := rssItem{
+			Title:       item.Title,
+			Link:        item.URL,
+			GUID:        rssGUID{Value: item.ID},
+			PubDate:     item.Published.Format(time.RFC1123Z),
+			Creator:     item.Author,
+			Categories:  item.Categories,
+			Description: item.Description,
+			Content:     item.Content,
+			Comments:    item.CommentsURL,
+		}
+		for _, enclosure := range item.Enclosures {
+			ri.Enclosures = append(ri.Enclosures, rssEnclosure{
+				URL:    enclosure.URL,
+				Length: enclosure.Length,
+				Type:   enclosure.Type,
+			})
+		}
+		// <source> requires the url of the original feed
+		if item.SourceURL != "" {
+			ri.Source = &rssSource{URL: item.SourceURL, Name: item.SourceName}
+		}
+		channel.Items = append(channel.Items, ri)
+	}
+
+	return writeXML(w, rssDocument{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Content: "http://purl.org/rss/1.0/modules/content/",
+		Channel: channel,
+	})
+}
@@ -0,0 +1,429 @@
+// Package htmltext renders the html of feed descriptions as plain text for
+// the terminal: paragraphs and lists survive, scripts and styles don't,
+// links become footnotes or OSC 8 hyperlinks and lines are wrapped.
+package htmltext
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// consts
+type LinkStyle int
+
+const (
+	// LinksFootnotes numbers links in the text and lists them at the end.
+	LinksFootnotes LinkStyle = iota
+	// LinksOSC8 makes link text clickable in terminals that support it.
+	LinksOSC8
+	// LinksNone keeps only the link text.
+	LinksNone
+)
+
+const ellipsis = "…"
+
+// structs
+
+// Options controls the output. The zero value wraps nothing, truncates
+// nothing and uses footnotes.
+type Options struct {
+	Width     int // wrap lines at this many characters, 0 disables wrapping
+	MaxLength int // truncate the text after this many characters, 0 disables it
+	Links     LinkStyle
+}
+
+// word is a run of visible text, possibly inside a link. glue words follow
+// the previous one without a space, e.g. the "bar" of foo<b>bar</b>.
+type word struct {
+	text string
+	href string
+	glue bool
+}
+
+// block is a paragraph-like unit, rendered as wrapped lines
+type block struct {
+	words  []word
+	prefix string // first line, e.g. a list bullet
+	indent string // following lines
+	pre    bool
+	raw    string // preformatted text
+	list   int    // the outermost list the block is in, 0 outside of lists
+}
+
+type renderer struct {
+	blocks  []block
+	current *block
+	indent  string
+	lists   []listState
+	listID  int
+	href    string
+	pending string // prefix for the next block, e.g. a list bullet
+	space   bool   // the last text ended in whitespace
+}
+
+type listState struct {
+	ordered bool
+	n       int
+}
+
+// functions
+
+// Render turns untrusted html into terminal text. Broken markup is
+// recovered the way browsers do it.
+func Render(src string, opts Options) string {
+	// feeds that double encode their CDATA leave the markers in the text
+	src = strings.ReplaceAll(src, "<![CDATA[", "")
+	src = strings.ReplaceAll(src, "]]>", "")
+
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(src), context)
+	if err != nil {
+		return strings.TrimSpace(src)
+	}
+
+	r := &renderer{}
+	for _, n := range nodes {
+		r.walk(n)
+	}
+	r.flush()
+	return r.output(opts)
+}
+
+func (r *renderer) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			r.walk(c)
+		}
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Head, atom.Iframe, atom.Object, atom.Svg:
+		return
+	case atom.Br:
+		r.flush()
+		return
+	case atom.Hr:
+		r.flush()
+		r.blocks = append(r.blocks, block{words: []word{{text: "───"}}, indent: r.indent})
+		return
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			r.word(word{text: "[image: " + alt + "]", href: r.href})
+		}
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.A:
+		href := strings.TrimSpace(attr(n, "href"))
+		if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			break
+		}
+		outer := r.href
+		r.href = href
+		r.children(n)
+		r.href = outer
+		return
+
+	case atom.Ul, atom.Ol:
+		r.flush()
+		if len(r.lists) == 0 {
+			r.listID++
+		}
+		r.lists = append(r.lists, listState{ordered: n.DataAtom == atom.Ol})
+		r.children(n)
+		r.lists = r.lists[:len(r.lists)-1]
+		r.flush()
+		return
+
+	case atom.Li:
+		r.flush()
+		bullet := "• "
+		if len(r.lists) > 0 {
+			list := &r.lists[len(r.lists)-1]
+			list.n++
+			if list.ordered {
+				bullet = strconv.Itoa(list.n) + ". "
+			}
+		}
+		outer := r.indent
+		r.pending = outer + bullet
+		r.indent = outer + strings.Repeat(" ", utf8.RuneCountInString(bullet))
+		r.children(n)
+		r.flush()
+		r.indent = outer
+		return
+
+	case atom.Blockquote:
+		r.flush()
+		outer := r.indent
+		r.indent = outer + "> "
+		r.children(n)
+		r.flush()
+		r.indent = outer
+		return
+
+	case atom.Pre:
+		r.flush()
+		var text strings.Builder
+		collectText(n, &text)
+		raw := stripControl(strings.Trim(text.String(), "\n"), "\n\t")
+		r.blocks = append(r.blocks, block{raw: raw, indent: r.indent, pre: true})
+		return
+
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Figure, atom.Figcaption,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Table, atom.Tr, atom.Dl, atom.Dt, atom.Dd:
+		r.flush()
+		r.children(n)
+		r.flush()
+		return
+	}
+
+	r.children(n)
+}
+
+func (r *renderer) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.walk(c)
+	}
+}
+
+func (r *renderer) text(s string) {
+	s = stripControl(s, "\n\t")
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		r.space = r.space || s != ""
+		return
+	}
+	startsWithSpace := strings.IndexFunc(s, isSpace) == 0
+	for i, field := range fields {
+		w := word{text: field, href: r.href}
+		if i == 0 && !startsWithSpace && !r.space {
+			w.glue = true
+		}
+		r.word(w)
+	}
+	r.space = strings.LastIndexFunc(s, isSpace) == len(s)-1
+}
+
+func (r *renderer) word(w word) {
+	if r.current == nil {
+		prefix := r.pending
+		if prefix == "" {
+			prefix = r.indent
+		}
+		r.pending = ""
+		r.current = &block{prefix: prefix, indent: r.indent, list: r.currentList()}
+		w.glue = false
+	}
+	r.current.words = append(r.current.words, w)
+}
+
+func (r *renderer) currentList() int {
+	if len(r.lists) == 0 {
+		return 0
+	}
+	return r.listID
+}
+
+// flush ends the current paragraph
+func (r *renderer) flush() {
+	if r.current != nil && len(r.current.words) > 0 {
+		r.blocks = append(r.blocks, *r.current)
+	}
+	r.current = nil
+	r.space = true
+}
+
+func (r *renderer) output(opts Options) string {
+	var links []string
+	linkNumbers := make(map[string]int)
+	remaining := opts.MaxLength
+	truncated := false
+
+	var out strings.Builder
+	var previous *block
+	for i := range r.blocks {
+		b := &r.blocks[i]
+		if opts.MaxLength > 0 && remaining <= 0 {
+			truncated = true
+			break
+		}
+
+		var paragraph string
+		if b.pre {
+			text := b.raw
+			if opts.MaxLength > 0 {
+				text, truncated = cut(text, remaining)
+				remaining -= utf8.RuneCountInString(text)
+			}
+			lines := strings.Split(text, "\n")
+			for i := range lines {
+				lines[i] = b.indent + lines[i]
+			}
+			paragraph = strings.Join(lines, "\n")
+		} else {
+			var words []word
+			for _, w := range b.words {
+				if opts.MaxLength > 0 {
+					length := utf8.RuneCountInString(w.text)
+					if length > remaining {
+						// cut a long word, drop a short one
+						truncated = true
+						if len(words) > 0 && remaining < length/2+1 {
+							break
+						}
+						w.text, _ = cut(w.text, remaining)
+					}
+					remaining -= length + 1
+				}
+				words = append(words, w)
+				if truncated {
+					break
+				}
+			}
+
+			// footnote numbers go after the last word of a link
+			for i := range words {
+				w := &words[i]
+				lastOfLink := w.href != "" && (i+1 == len(words) || words[i+1].href != w.href)
+				if !lastOfLink || opts.Links != LinksFootnotes {
+					continue
+				}
+				n, ok := linkNumbers[w.href]
+				if !ok {
+					links = append(links, w.href)
+					n = len(links)
+					linkNumbers[w.href] = n
+				}
+				w.text += "[" + strconv.Itoa(n) + "]"
+			}
+			if len(words) == 0 {
+				continue
+			}
+			paragraph = wrap(words, b.prefix, b.indent, opts)
+		}
+
+		// items of a list follow each other without a blank line
+		switch {
+		case previous == nil:
+		case b.list != 0 && previous.list == b.list:
+			out.WriteString("\n")
+		default:
+			out.WriteString("\n\n")
+		}
+		out.WriteString(paragraph)
+		previous = b
+		if truncated {
+			break
+		}
+	}
+
+	if truncated {
+		out.WriteString(ellipsis)
+	}
+	if len(links) > 0 {
+		out.WriteString("\n")
+		for i, link := range links {
+			fmt.Fprintf(&out, "\n[%d] %s", i+1, link)
+		}
+	}
+	return out.String()
+}
+
+// wrap lays words out on lines of at most opts.Width visible characters.
+// A word longer than a line gets a line of its own.
+func wrap(words []word, prefix, indent string, opts Options) string {
+	var out strings.Builder
+	out.WriteString(prefix)
+	lineLength := utf8.RuneCountInString(prefix)
+	atLineStart := true
+
+	for _, w := range words {
+		length := utf8.RuneCountInString(w.text)
+		if !atLineStart && !w.glue {
+			if opts.Width > 0 && lineLength+1+length > opts.Width {
+				out.WriteString("\n" + indent)
+				lineLength = utf8.RuneCountInString(indent)
+			} else {
+				out.WriteString(" ")
+				lineLength++
+			}
+		}
+		if w.href != "" && opts.Links == LinksOSC8 {
+			out.WriteString("\033]8;;" + sanitizeHref(w.href) + "\033\\" + w.text + "\033]8;;\033\\")
+		} else {
+			out.WriteString(w.text)
+		}
+		lineLength += length
+		atLineStart = false
+	}
+	return out.String()
+}
+
+// helpers
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func collectText(n *html.Node, out *strings.Builder) {
+	if n.Type == html.TextNode {
+		out.WriteString(n.Data)
+		return
+	}
+	if n.Type == html.ElementNode && n.DataAtom == atom.Br {
+		out.WriteString("\n")
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectText(c, out)
+	}
+}
+
+// cut shortens s to at most n characters and reports whether it did
+func cut(s string, n int) (string, bool) {
+	if n <= 0 {
+		return "", s != ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s, false
+	}
+	return string([]rune(s)[:n]), true
+}
+
+// sanitizeHref keeps control characters of untrusted urls out of the
+// escape sequence
+func sanitizeHref(href string) string {
+	return stripControl(href, "")
+}
+
+// stripControl removes control characters, e.g. escape sequences hidden in
+// a feed, except for the ones in keep
+func stripControl(s, keep string) string {
+	return strings.Map(func(r rune) rune {
+		if (r < ' ' || r == 0x7f || (r >= 0x80 && r < 0xa0)) && !strings.ContainsRune(keep, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// consts
+const defaultSMTPPort = 587
+
+// structs
+
+// Mailer sends mail through an SMTP server. It authenticates when Username
+// is set, net/smtp only allows that over TLS or to localhost.
+type Mailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// functions
+
+// Email sends the message as a plain text mail to to.
+func (m Mailer) Email(to string, msg Message) error {
+	return m.Send(to, "gator: "+msg.Subject(), "text/plain", msg.Text())
+}
+
+// Send mails body to one address. contentType is e.g. text/plain or
+// text/html, the charset is always UTF-8.
+func (m Mailer) Send(to, subject, contentType, body string) error {
+	if m.Host == "" || m.From == "" {
+		return errors.New("no smtp server configured, set smtp.host and smtp.from in the config")
+	}
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("invalid smtp.from: %w", err)
+	}
+	rcpt, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid address %s: %w", to, err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", rcpt)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&msg)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	if err := qp.Close(); err != nil {
+		return err
+	}
+
+	port := m.Port
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(port))
+	return smtp.SendMail(addr, auth, from.Address, []string{rcpt.Address}, msg.Bytes())
+}
@@ -0,0 +1,101 @@
+// Package notify delivers notifications about new posts: on the desktop
+// through notify-send, as a JSON POST to a webhook or by email. One message
+// carries one post, or several when they are sent together.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// consts
+const webhookTimeout = 10 * time.Second
+
+// structs
+
+// Post is a post a message is about.
+type Post struct {
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Feed        string    `json:"feed"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Message tells about the posts that matched a watch.
+type Message struct {
+	WatchID uuid.UUID `json:"watch_id"`
+	Watch   string    `json:"watch"` // what the watch looks for, e.g. `title ~ "cve"`
+	Posts   []Post    `json:"posts"`
+}
+
+// functions
+
+// Subject is the title of a single post, or a count for several.
+func (m Message) Subject() string {
+	if len(m.Posts) == 1 {
+		return m.Posts[0].Title
+	}
+	return fmt.Sprintf("%d new posts for %s", len(m.Posts), m.Watch)
+}
+
+// Text lists the posts, with their feed and link, in plain text.
+func (m Message) Text() string {
+	var b strings.Builder
+	for i, post := range m.Posts {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s\n%s, %s\n%s\n", post.Title, post.Feed, post.PublishedAt.Format("2006-01-02 15:04"), post.URL)
+	}
+	return b.String()
+}
+
+// Desktop shows the message with notify-send, on the desktop of whoever
+// runs the aggregator.
+func Desktop(ctx context.Context, m Message) error {
+	out, err := exec.CommandContext(ctx, "notify-send", "--app-name=gator", m.Subject(), m.Text()).CombinedOutput()
+	if errors.Is(err, exec.ErrNotFound) {
+		return errors.New("notify-send not found, install libnotify")
+	}
+	if err != nil {
+		return fmt.Errorf("notify-send: %w: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// Webhook POSTs the message as JSON to url, any 2xx answer counts as
+// delivered.
+func Webhook(ctx context.Context, url string, m Message) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gator")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", res.Status)
+	}
+	return nil
+}
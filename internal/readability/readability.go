@@ -0,0 +1,426 @@
+// Package readability finds the article in a web page: the element whose
+// paragraphs score highest, with navigation, comments, ads and other
+// boilerplate dropped. It follows the scoring of Arc90's readability.
+package readability
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// consts
+
+// MinTextLength is how much text an article needs, anything shorter is
+// most likely not the content of the page.
+const MinTextLength = 250
+
+// errors
+var ErrNoArticle = errors.New("no article found in page")
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|yom-remote|cookie|newsletter|subscribe|share`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveNames      = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	negativeNames      = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// structs
+type scorer struct {
+	scores map[*html.Node]float64
+	order  []*html.Node // candidates in document order, for stable ties
+}
+
+// functions
+
+// Extract returns the main content of page as cleaned html: no scripts,
+// styles, forms or attributes beyond links and images, whose urls are made
+// absolute against pageURL.
+func Extract(page []byte, pageURL string) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return "", err
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+	if href := baseHref(doc); href != "" {
+		if u, err := base.Parse(href); err == nil {
+			base = u
+		}
+	}
+
+	body := find(doc, atom.Body)
+	if body == nil {
+		return "", ErrNoArticle
+	}
+	removeAll(body, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Iframe, atom.Object, atom.Embed,
+			atom.Svg, atom.Form, atom.Button, atom.Input, atom.Select, atom.Textarea, atom.Nav, atom.Aside:
+			return true
+		}
+		if n.Type == html.CommentNode {
+			return true
+		}
+		return n.Type == html.ElementNode && isHidden(n)
+	})
+	removeAll(body, unlikely)
+
+	top := bestCandidate(body)
+	if top == nil {
+		return "", ErrNoArticle
+	}
+	clean(top)
+	if utf8.RuneCountInString(strings.TrimSpace(textContent(top))) < MinTextLength {
+		return "", ErrNoArticle
+	}
+	absolutize(top, base)
+
+	var out bytes.Buffer
+	for c := top.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&out, c); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// bestCandidate scores the parents of every paragraph and returns the
+// winner together with the siblings that look like they belong to it,
+// wrapped in a new div
+func bestCandidate(body *html.Node) *html.Node {
+	s := &scorer{scores: make(map[*html.Node]float64)}
+
+	walk(body, func(n *html.Node) {
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td, atom.Blockquote, atom.Section, atom.H2, atom.H3:
+		case atom.Div:
+			// divs of bare text count as paragraphs
+			if hasBlockChildren(n) {
+				return
+			}
+		default:
+			return
+		}
+		text := strings.TrimSpace(textContent(n))
+		length := utf8.RuneCountInString(text)
+		if length < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(length)/100, 3)
+
+		parent := n.Parent
+		for level := 0; parent != nil && parent.Type == html.ElementNode && level < 3; level++ {
+			s.init(parent)
+			switch level {
+			case 0:
+				s.scores[parent] += score
+			case 1:
+				s.scores[parent] += score / 2
+			default:
+				s.scores[parent] += score / 6
+			}
+			parent = parent.Parent
+		}
+	})
+
+	var top *html.Node
+	topScore := 0.0
+	for _, n := range s.order {
+		if n == body {
+			continue
+		}
+		score := s.scores[n] * (1 - linkDensity(n))
+		s.scores[n] = score
+		if top == nil || score > topScore {
+			top, topScore = n, score
+		}
+	}
+	if top == nil {
+		if hasText(body) {
+			top = body
+		} else {
+			return nil
+		}
+	}
+
+	// siblings of the winner with a good score or a real paragraph are
+	// part of the article too, e.g. when each paragraph has its own div
+	article := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	if top.Parent == nil || top == body {
+		moveChildren(top, article)
+		return article
+	}
+	threshold := max(10, topScore*0.2)
+	var keep []*html.Node
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling == top {
+			keep = append(keep, sibling)
+			continue
+		}
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		if score, ok := s.scores[sibling]; ok && score >= threshold {
+			keep = append(keep, sibling)
+			continue
+		}
+		if sibling.DataAtom == atom.P {
+			text := textContent(sibling)
+			length := utf8.RuneCountInString(strings.TrimSpace(text))
+			density := linkDensity(sibling)
+			if (length > 80 && density < 0.25) || (length > 0 && density == 0 && strings.Contains(text, ". ")) {
+				keep = append(keep, sibling)
+			}
+		}
+	}
+	for _, n := range keep {
+		n.Parent.RemoveChild(n)
+		article.AppendChild(n)
+	}
+	return article
+}
+
+func (s *scorer) init(n *html.Node) {
+	if _, ok := s.scores[n]; ok {
+		return
+	}
+	score := classWeight(n)
+	switch n.DataAtom {
+	case atom.Div, atom.Article, atom.Main:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	s.scores[n] = score
+	s.order = append(s.order, n)
+}
+
+// clean drops what is left of the page furniture inside the article:
+// link lists, empty blocks and all attributes but the few readers need
+func clean(article *html.Node) {
+	removeAll(article, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		switch n.DataAtom {
+		case atom.H1:
+			// the title is shown with the post already
+			return true
+		case atom.Ul, atom.Ol, atom.Div, atom.Section, atom.Table, atom.Header, atom.Footer:
+			weight := classWeight(n)
+			text := utf8.RuneCountInString(strings.TrimSpace(textContent(n)))
+			if weight < 0 {
+				return true
+			}
+			if text == 0 {
+				return find(n, atom.Img) == nil && find(n, atom.Pre) == nil
+			}
+			density := linkDensity(n)
+			return (density > 0.5 && weight < 25) || (text < 25 && density > 0.2 && find(n, atom.Img) == nil)
+		case atom.P:
+			return strings.TrimSpace(textContent(n)) == "" && find(n, atom.Img) == nil
+		}
+		return false
+	})
+
+	walk(article, func(n *html.Node) {
+		var attrs []html.Attribute
+		for _, a := range n.Attr {
+			switch a.Key {
+			case "href", "src", "alt", "title":
+				attrs = append(attrs, a)
+			case "data-src":
+				// lazy loaded images
+				if n.DataAtom == atom.Img && attr(n, "src") == "" {
+					attrs = append(attrs, html.Attribute{Key: "src", Val: a.Val})
+				}
+			}
+		}
+		n.Attr = attrs
+	})
+}
+
+// absolutize resolves the links and image sources of the article against
+// the page, dropping the ones that aren't http(s)
+func absolutize(article *html.Node, base *url.URL) {
+	walk(article, func(n *html.Node) {
+		for i := range n.Attr {
+			a := &n.Attr[i]
+			if a.Key != "href" && a.Key != "src" {
+				continue
+			}
+			u, err := base.Parse(strings.TrimSpace(a.Val))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				a.Val = ""
+				continue
+			}
+			a.Val = u.String()
+		}
+	})
+}
+
+// helpers
+func unlikely(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	switch n.DataAtom {
+	case atom.Body, atom.A, atom.Article, atom.Main:
+		return false
+	case atom.Header, atom.Footer:
+		return true
+	}
+	if attr(n, "role") == "navigation" || attr(n, "role") == "complementary" || attr(n, "aria-modal") == "true" {
+		return true
+	}
+	names := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidates.MatchString(names) && !maybeCandidate.MatchString(names)
+}
+
+func isHidden(n *html.Node) bool {
+	if _, ok := attrOK(n, "hidden"); ok || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(attr(n, "style")), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, name := range []string{attr(n, "class"), attr(n, "id")} {
+		if name == "" {
+			continue
+		}
+		if negativeNames.MatchString(name) {
+			weight -= 25
+		}
+		if positiveNames.MatchString(name) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of the text of n that is inside links
+func linkDensity(n *html.Node) float64 {
+	total := utf8.RuneCountInString(strings.TrimSpace(textContent(n)))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	walk(n, func(c *html.Node) {
+		if c.DataAtom == atom.A {
+			linked += utf8.RuneCountInString(strings.TrimSpace(textContent(c)))
+		}
+	})
+	return float64(linked) / float64(total)
+}
+
+func hasBlockChildren(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.DataAtom {
+		case atom.P, atom.Div, atom.Section, atom.Article, atom.Table, atom.Ul, atom.Ol, atom.Pre, atom.Blockquote,
+			atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Figure:
+			return true
+		}
+	}
+	return false
+}
+
+func hasText(n *html.Node) bool {
+	return strings.TrimSpace(textContent(n)) != ""
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return b.String()
+}
+
+// walk calls f for every element below n, in document order
+func walk(n *html.Node, f func(*html.Node)) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			f(c)
+		}
+		walk(c, f)
+	}
+}
+
+// removeAll removes the nodes below n that match, without looking inside them
+func removeAll(n *html.Node, match func(*html.Node) bool) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if match(c) {
+			n.RemoveChild(c)
+		} else {
+			removeAll(c, match)
+		}
+		c = next
+	}
+}
+
+func moveChildren(from, to *html.Node) {
+	for c := from.FirstChild; c != nil; c = from.FirstChild {
+		from.RemoveChild(c)
+		to.AppendChild(c)
+	}
+}
+
+func find(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := find(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func baseHref(doc *html.Node) string {
+	if head := find(doc, atom.Head); head != nil {
+		if base := find(head, atom.Base); base != nil {
+			return attr(base, "href")
+		}
+	}
+	return ""
+}
+
+func attr(n *html.Node, key string) string {
+	val, _ := attrOK(n, key)
+	return val
+}
+
+func attrOK(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,61 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// newXMLDecoder returns a decoder that yields UTF-8 regardless of the feed's
+// encoding. A charset in the Content-Type header wins over the XML
+// declaration (RFC 7303), otherwise the declaration is honored.
+func newXMLDecoder(body []byte, contentType string) (*xml.Decoder, error) {
+	var r io.Reader = bytes.NewReader(body)
+
+	fromHeader := false
+	if label := headerCharset(contentType); label != "" {
+		if !isUTF8(label) {
+			cr, err := charset.NewReaderLabel(label, r)
+			if err != nil {
+				return nil, fmt.Errorf("unsupported charset %q: %w", label, err)
+			}
+			r = cr
+		}
+		fromHeader = true
+	}
+
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		if fromHeader {
+			// already UTF-8, the header overrides the declaration
+			return input, nil
+		}
+		cr, err := charset.NewReaderLabel(label, input)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported charset %q: %w", label, err)
+		}
+		return cr, nil
+	}
+	return decoder, nil
+}
+
+func headerCharset(contentType string) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(params["charset"])
+}
+
+func isUTF8(label string) bool {
+	switch strings.ToLower(label) {
+	case "utf-8", "utf8":
+		return true
+	}
+	return false
+}
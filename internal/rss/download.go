@@ -0,0 +1,124 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Progress is called while a download runs. total is -1 when the server
+// doesn't say how big the file is.
+type Progress func(written, total int64)
+
+// Download fetches rawURL, e.g. a podcast enclosure, into path. The body is
+// written to path+".part" first, an existing partial file is resumed with a
+// range request when the server supports it. Host options apply, so private
+// feeds can serve their enclosures with the same credentials. There is no
+// timeout, ctx controls how long it may take.
+func (c *Client) Download(ctx context.Context, rawURL, path string, progress Progress) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return err
+	}
+	opts := c.optionsFor(req.URL.Hostname(), "")
+	req.Header.Set("User-Agent", opts.UserAgent)
+	opts.apply(req)
+
+	partial := path + ".part"
+	var offset int64
+	if info, err := os.Stat(partial); err == nil {
+		offset = info.Size()
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+
+	client := &http.Client{Transport: c.Transport}
+	res, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = opts.redact(urlErr.URL)
+		}
+		return err
+	}
+	defer res.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case res.StatusCode == http.StatusPartialContent && offset > 0:
+		flags |= os.O_APPEND
+	case res.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		if rangeComplete(res, offset) {
+			return os.Rename(partial, path)
+		}
+		// the file changed since, start over
+		res.Body.Close()
+		if err := os.Remove(partial); err != nil {
+			return err
+		}
+		return c.Download(ctx, rawURL, path, progress)
+	case res.StatusCode >= 400:
+		return &HTTPError{URL: RedactURL(rawURL), StatusCode: res.StatusCode, Status: res.Status}
+	default:
+		// no range support, start over
+		flags |= os.O_TRUNC
+		offset = 0
+	}
+
+	total := int64(-1)
+	if res.ContentLength >= 0 {
+		total = offset + res.ContentLength
+	}
+
+	file, err := os.OpenFile(partial, flags, 0644)
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(file, &progressReader{r: res.Body, written: offset, total: total, progress: progress})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("download interrupted, run it again to resume: %w", err)
+	}
+	if total >= 0 && offset+written < total {
+		return fmt.Errorf("download incomplete (%d of %d bytes), run it again to resume", offset+written, total)
+	}
+	return os.Rename(partial, path)
+}
+
+// helpers
+
+// rangeComplete reports whether a 416 answer says the file has size bytes,
+// so a partial file of that size already has all of it
+func rangeComplete(res *http.Response, size int64) bool {
+	rest, ok := strings.CutPrefix(res.Header.Get("Content-Range"), "bytes */")
+	if !ok {
+		return false
+	}
+	total, err := strconv.ParseInt(rest, 10, 64)
+	return err == nil && total == size
+}
+
+type progressReader struct {
+	r        io.Reader
+	written  int64
+	total    int64
+	progress Progress
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.written += int64(n)
+	if p.progress != nil && n > 0 {
+		p.progress(p.written, p.total)
+	}
+	return n, err
+}
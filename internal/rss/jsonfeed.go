@@ -0,0 +1,184 @@
+package rss
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+)
+
+// JSON Feed (https://jsonfeed.org), versions 1.0 and 1.1. Only the fields
+// gator stores are decoded.
+
+// consts
+const jsonFeedVersionPrefix = "https://jsonfeed.org/version/"
+
+// structs
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Icon        string         `json:"icon"`
+	Authors     []jsonAuthor   `json:"authors"`
+	Author      *jsonAuthor    `json:"author"` // 1.0
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            json.RawMessage  `json:"id"`
+	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	ContentText   string           `json:"content_text"`
+	Summary       string           `json:"summary"`
+	Image         string           `json:"image"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []jsonAuthor     `json:"authors"`
+	Author        *jsonAuthor      `json:"author"` // 1.0
+	Tags          []string         `json:"tags"`
+	Attachments   []jsonAttachment `json:"attachments"`
+}
+
+type jsonAttachment struct {
+	URL         string  `json:"url"`
+	MimeType    string  `json:"mime_type"`
+	SizeInBytes int64   `json:"size_in_bytes"`
+	Duration    float64 `json:"duration_in_seconds"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// functions
+
+// isJSONFeed reports whether the response should be parsed as JSON Feed,
+// by content type or, for the usual wrong types, by looking at the body.
+func isJSONFeed(contentType string, body []byte) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/feed+json" || mediaType == "application/json" {
+		return true
+	}
+	trimmed := bytes.TrimLeft(body, "\xef\xbb\xbf \t\r\n")
+	return bytes.HasPrefix(trimmed, []byte("{")) && bytes.Contains(body, []byte("jsonfeed.org/version"))
+}
+
+// parseJSONFeed maps a JSON Feed onto RSSFeed so it is stored like any
+// other feed. Dates are left in RFC 3339 as the spec requires.
+func parseJSONFeed(body []byte) (*RSSFeed, error) {
+	var feed jsonFeed
+	if err := json.Unmarshal(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")), &feed); err != nil {
+		return nil, fmt.Errorf("parsing json feed: %w", err)
+	}
+	if !strings.HasPrefix(feed.Version, jsonFeedVersionPrefix) {
+		return nil, errors.New("parsing json feed: missing jsonfeed.org version")
+	}
+
+	var rss RSSFeed
+	rss.Channel.Title = feed.Title
+	rss.Channel.Link = feed.HomePageURL
+	rss.Channel.Description = feed.Description
+	rss.Channel.Image = feed.Icon
+
+	feedAuthor := authorNames(feed.Authors, feed.Author)
+	for _, item := range feed.Items {
+		id := jsonFeedID(item.ID)
+
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+		if link == "" && (strings.HasPrefix(id, "http://") || strings.HasPrefix(id, "https://")) {
+			link = id
+		}
+
+		// like description and content:encoded in RSS, the summary is the
+		// description when there is one
+		content := item.ContentHTML
+		if content == "" {
+			content = item.ContentText
+		}
+		description := item.Summary
+		if description == "" {
+			description, content = content, ""
+		}
+
+		var enclosures []Enclosure
+		var duration time.Duration
+		for _, attachment := range item.Attachments {
+			if attachment.URL == "" {
+				continue
+			}
+			if duration == 0 && attachment.Duration > 0 {
+				duration = time.Duration(attachment.Duration * float64(time.Second))
+			}
+			enclosures = append(enclosures, Enclosure{
+				URL:    attachment.URL,
+				Length: max(attachment.SizeInBytes, 0),
+				Type:   attachment.MimeType,
+			})
+		}
+
+		published := item.DatePublished
+		if published == "" {
+			published = item.DateModified
+		}
+
+		author := authorNames(item.Authors, item.Author)
+		if author == "" {
+			author = feedAuthor
+		}
+
+		rss.Channel.Item = append(rss.Channel.Item, RSSItem{
+			Title:       item.Title,
+			Link:        link,
+			Description: description,
+			PubDate:     published,
+			GUID:        id,
+			Author:      author,
+			Categories:  cleanCategories(item.Tags),
+			Enclosures:  enclosures,
+			Content:     content,
+			Duration:    duration,
+			Image:       cmp.Or(item.Image, feed.Icon),
+		})
+	}
+	return &rss, nil
+}
+
+// helpers
+
+// jsonFeedID returns the item id as a string. The spec says string, but
+// some generators emit numbers.
+func jsonFeedID(raw json.RawMessage) string {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+	var number json.Number
+	if err := json.Unmarshal(raw, &number); err == nil {
+		return number.String()
+	}
+	return ""
+}
+
+func authorNames(authors []jsonAuthor, author *jsonAuthor) string {
+	if author != nil {
+		authors = append(authors, *author)
+	}
+	names := make([]string, 0, len(authors))
+	for _, a := range authors {
+		if a.Name != "" {
+			names = append(names, a.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
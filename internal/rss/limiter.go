@@ -0,0 +1,91 @@
+package rss
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostLimiter keeps a minimum delay between requests to the same host and
+// caps how many run at once.
+type hostLimiter struct {
+	mu    sync.Mutex
+	hosts map[string]*hostSlot
+}
+
+type hostSlot struct {
+	sem  chan struct{}
+	next time.Time
+}
+
+func newHostLimiter() *hostLimiter {
+	return &hostLimiter{hosts: make(map[string]*hostSlot)}
+}
+
+// acquire blocks until a request to host may start. The returned func must
+// be called once the request is done.
+func (l *hostLimiter) acquire(ctx context.Context, host string, minDelay time.Duration, maxConcurrency int) (func(), error) {
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1
+	}
+	host = strings.ToLower(host)
+
+	l.mu.Lock()
+	slot, ok := l.hosts[host]
+	if !ok {
+		slot = &hostSlot{sem: make(chan struct{}, maxConcurrency)}
+		l.hosts[host] = slot
+	}
+	l.mu.Unlock()
+
+	select {
+	case slot.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-slot.sem }
+
+	// reserve the next start time, then wait for it outside the lock
+	l.mu.Lock()
+	now := time.Now()
+	start := slot.next
+	if start.Before(now) {
+		start = now
+	}
+	slot.next = start.Add(minDelay)
+	l.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// parseRetryAfter reads a Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Page is a fetched html document, e.g. the article a post links to. Body
+// is UTF-8, URL is where the page ended up after redirects.
+type Page struct {
+	URL  string
+	Body []byte
+}
+
+// FetchPage fetches an html page with the client's limits, politeness and
+// host options. Responses that aren't html fail with a ContentTypeError.
+func (c *Client) FetchPage(ctx context.Context, pageURL string) (*Page, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	opts := c.optionsFor(req.URL.Hostname(), "")
+	req.Header.Set("User-Agent", opts.UserAgent)
+	req.Header.Set("Accept", "text/html, application/xhtml+xml;q=0.9, */*;q=0.1")
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	opts.apply(req)
+
+	client := &http.Client{
+		Transport: c.Transport,
+		Timeout:   opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= c.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			return nil
+		},
+	}
+	release, err := c.limiter.acquire(ctx, req.URL.Hostname(), c.MinHostDelay, c.MaxHostConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	res, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = opts.redact(urlErr.URL)
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return nil, &HTTPError{URL: RedactURL(pageURL), StatusCode: res.StatusCode, Status: res.Status}
+	}
+
+	contentType := res.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch mediaType {
+		case "text/html", "application/xhtml+xml":
+		default:
+			return nil, &ContentTypeError{URL: RedactURL(pageURL), ContentType: mediaType}
+		}
+	}
+
+	body, err := c.readBody(res)
+	if err != nil {
+		return nil, err
+	}
+
+	// the header charset, a <meta> tag or a guess from the content
+	r, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		return nil, err
+	}
+	body, err = io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page{URL: opts.redact(res.Request.URL.String()), Body: body}, nil
+}
@@ -1,22 +1,89 @@
 package rss
 
 import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
-	"html"
-	"io"
-	"encoding/xml"
+
+	"github.com/andybalholm/brotli"
+	"github.com/curator4/gator/internal/urlcanon"
+)
+
+// consts
+const (
+	DefaultUserAgent    = "gator"
+	DefaultTimeout      = 5 * time.Second
+	DefaultMaxBodySize  = 10 << 20
+	DefaultMaxRedirects = 5
+
+	DefaultMinHostDelay       = time.Second
+	DefaultMaxHostConcurrency = 1
+)
+
+// errors
+var (
+	ErrBodyTooLarge     = errors.New("feed body exceeds maximum size")
+	ErrTooManyRedirects = errors.New("too many redirects")
 )
 
+// HTTPError is returned when the feed server answers with a 4xx or 5xx status.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	Status     string
+
+	// RetryAfter is the delay asked for by a 429 or 503 response, if any.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("fetching %s: %s (retry after %v)", e.URL, e.Status, e.RetryAfter)
+	}
+	return fmt.Sprintf("fetching %s: %s", e.URL, e.Status)
+}
+
+// Throttled reports whether the server asked us to back off.
+func (e *HTTPError) Throttled() bool {
+	return e.StatusCode == http.StatusTooManyRequests ||
+		(e.StatusCode == http.StatusServiceUnavailable && e.RetryAfter > 0)
+}
+
+// ContentTypeError is returned when the response is clearly not a feed,
+// e.g. an HTML error page served with a 200.
+type ContentTypeError struct {
+	URL         string
+	ContentType string
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("fetching %s: unsupported content type %q", e.URL, e.ContentType)
+}
+
 // structs
 type RSSFeed struct {
 	Channel struct {
 		Title       string    `xml:"title"`
 		Link        string    `xml:"link"`
 		Description string    `xml:"description"`
+		Image       string    `xml:"-"` // podcast artwork
 		Item        []RSSItem `xml:"item"`
 	} `xml:"channel"`
+
+	// PermanentURL is set when the feed was only reached through permanent
+	// (301/308) redirects, so callers can update the stored URL.
+	PermanentURL string `xml:"-"`
 }
 
 type RSSItem struct {
@@ -24,43 +91,379 @@ type RSSItem struct {
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	GUID        string `xml:"guid"`
+	Author      string `xml:"author"`
+
+	Categories []string    `xml:"category"`
+	Enclosures []Enclosure `xml:"enclosure"`
+	Content    string      `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Comments   string      `xml:"comments"`
+
+	// podcast episodes, from itunes: tags. Image falls back to the artwork
+	// of the channel.
+	Duration time.Duration `xml:"-"`
+	Image    string        `xml:"-"`
+}
+
+// Enclosure is an attached file, e.g. a podcast episode. Length is in bytes
+// and 0 when the feed doesn't know it.
+type Enclosure struct {
+	URL    string
+	Length int64
+	Type   string
+}
+
+// Client fetches feeds. The zero value is not usable, use NewClient.
+type Client struct {
+	Transport    http.RoundTripper
+	UserAgent    string
+	Timeout      time.Duration
+	MaxBodySize  int64
+	MaxRedirects int
+
+	// politeness towards a single host, shared by all fetches of the client
+	MinHostDelay       time.Duration
+	MaxHostConcurrency int
+	limiter            *hostLimiter
+
+	// HostOptions is keyed by hostname and also applies to subdomains,
+	// FeedOptions is keyed by canonical feed url and wins over HostOptions.
+	HostOptions map[string]RequestOptions
+	FeedOptions map[string]RequestOptions
+}
+
+// RequestOptions overrides the client defaults for some requests. Empty
+// fields leave the less specific setting alone.
+type RequestOptions struct {
+	UserAgent   string
+	Timeout     time.Duration
+	Headers     map[string]string
+	Username    string
+	Password    string
+	BearerToken string
+
+	// Query is added to the request url, for feeds that take a token
+	// parameter. It is stripped from errors and redirect targets.
+	Query map[string]string
 }
 
 // functions
-func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+func NewClient() *Client {
+	return &Client{
+		Transport:    http.DefaultTransport,
+		UserAgent:    DefaultUserAgent,
+		Timeout:      DefaultTimeout,
+		MaxBodySize:  DefaultMaxBodySize,
+		MaxRedirects: DefaultMaxRedirects,
+
+		MinHostDelay:       DefaultMinHostDelay,
+		MaxHostConcurrency: DefaultMaxHostConcurrency,
+		limiter:            newHostLimiter(),
+	}
+}
+
+// FetchFeed fetches feedURL with the default client settings.
+func FetchFeed(ctx context.Context, feedURL string, overrides ...RequestOptions) (*RSSFeed, error) {
+	return NewClient().FetchFeed(ctx, feedURL, overrides...)
+}
+
+// FetchFeed fetches and parses feedURL. overrides are layered over the host
+// and feed options, e.g. credentials stored with the feed.
+func (c *Client) FetchFeed(ctx context.Context, feedURL string, overrides ...RequestOptions) (*RSSFeed, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("User-Agent", "gator")
+	opts := c.optionsFor(req.URL.Hostname(), feedURL)
+	for _, o := range overrides {
+		opts.merge(o)
+	}
+
+	req.Header.Set("User-Agent", opts.UserAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, text/xml;q=0.9, */*;q=0.1")
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	opts.apply(req)
 
+	// only a chain made entirely of permanent redirects moves the feed
+	permanent, redirected := true, false
 	client := &http.Client{
-		Timeout: 5 * time.Second,
+		Transport: c.Transport,
+		Timeout:   opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= c.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			redirected = true
+			switch req.Response.StatusCode {
+			case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			default:
+				permanent = false
+			}
+			return nil
+		},
 	}
+	release, err := c.limiter.acquire(ctx, req.URL.Hostname(), c.MinHostDelay, c.MaxHostConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	res, err := client.Do(req)
 	if err != nil {
+		// don't leak query credentials into logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = opts.redact(urlErr.URL)
+		}
 		return nil, err
 	}
 	defer res.Body.Close()
 
-	body, err := io.ReadAll(res.Body)
+	if res.StatusCode >= 400 {
+		httpErr := &HTTPError{URL: feedURL, StatusCode: res.StatusCode, Status: res.Status}
+		switch res.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			httpErr.RetryAfter = parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+		}
+		return nil, httpErr
+	}
+
+	body, err := c.readBody(res)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkContentType(res.Header.Get("Content-Type"), body); err != nil {
+		err.URL = feedURL
+		return nil, err
+	}
+
+	rss, err := parseFeed(body, res.Header.Get("Content-Type"))
 	if err != nil {
 		return nil, err
 	}
 
-	var rss RSSFeed
-	if err := xml.Unmarshal(body, &rss); err != nil {
+	if finalURL := opts.redact(res.Request.URL.String()); redirected && permanent && finalURL != feedURL {
+		rss.PermanentURL = finalURL
+	}
+
+	return rss, nil
+}
+
+// parseFeed decodes a JSON Feed or an XML feed, depending on what the
+// response looks like.
+func parseFeed(body []byte, contentType string) (*RSSFeed, error) {
+	if isJSONFeed(contentType, body) {
+		return parseJSONFeed(body)
+	}
+	return parseXMLFeed(body, contentType)
+}
+
+// RedactURL hides credentials that older feeds may still carry in their url,
+// for any output that lists feeds.
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	query := u.Query()
+	redacted := false
+	for key := range query {
+		switch strings.ToLower(key) {
+		case "token", "access_token", "private_token", "feed_token", "api_key", "apikey", "key", "password", "secret", "auth":
+			query.Set(key, "xxxxx")
+			redacted = true
+		}
+	}
+	if redacted {
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// helpers
+
+// optionsFor layers the host options (parent domains first) and then the
+// feed options over the client defaults.
+func (c *Client) optionsFor(host, feedURL string) RequestOptions {
+	opts := RequestOptions{
+		UserAgent: c.UserAgent,
+		Timeout:   c.Timeout,
+	}
+
+	host = strings.ToLower(host)
+	labels := strings.Split(host, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		if o, ok := c.HostOptions[strings.Join(labels[i:], ".")]; ok {
+			opts.merge(o)
+		}
+	}
+	// under either scheme, a feed stored as http may be fetched over https
+	for _, variant := range urlcanon.Variants(feedURL) {
+		if o, ok := c.FeedOptions[variant]; ok {
+			opts.merge(o)
+			break
+		}
+	}
+	return opts
+}
+
+func (o *RequestOptions) merge(other RequestOptions) {
+	if other.UserAgent != "" {
+		o.UserAgent = other.UserAgent
+	}
+	if other.Timeout > 0 {
+		o.Timeout = other.Timeout
+	}
+	if len(other.Headers) > 0 {
+		headers := make(map[string]string, len(o.Headers)+len(other.Headers))
+		for k, v := range o.Headers {
+			headers[k] = v
+		}
+		for k, v := range other.Headers {
+			headers[k] = v
+		}
+		o.Headers = headers
+	}
+	if other.Username != "" || other.Password != "" {
+		o.Username, o.Password = other.Username, other.Password
+		o.BearerToken = ""
+	}
+	if other.BearerToken != "" {
+		o.BearerToken = other.BearerToken
+		o.Username, o.Password = "", ""
+	}
+	if len(other.Query) > 0 {
+		query := make(map[string]string, len(o.Query)+len(other.Query))
+		for k, v := range o.Query {
+			query[k] = v
+		}
+		for k, v := range other.Query {
+			query[k] = v
+		}
+		o.Query = query
+	}
+}
+
+func (o RequestOptions) apply(req *http.Request) {
+	for k, v := range o.Headers {
+		req.Header.Set(k, v)
+	}
+	if o.Username != "" || o.Password != "" {
+		req.SetBasicAuth(o.Username, o.Password)
+	}
+	if o.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+o.BearerToken)
+	}
+	if len(o.Query) > 0 {
+		query := req.URL.Query()
+		for k, v := range o.Query {
+			query.Set(k, v)
+		}
+		req.URL.RawQuery = query.Encode()
+	}
+}
+
+// redact removes the Query parameters from rawURL.
+func (o RequestOptions) redact(rawURL string) string {
+	if len(o.Query) == 0 {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	for k := range o.Query {
+		query.Del(k)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// readBody decodes the content encoding and enforces MaxBodySize on the
+// decoded stream, so a small compressed bomb can't get around the limit.
+func (c *Client) readBody(res *http.Response) ([]byte, error) {
+	var r io.Reader = res.Body
+	switch strings.ToLower(strings.TrimSpace(res.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(res.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	case "deflate":
+		// "deflate" is supposed to be zlib-wrapped, but plenty of servers send raw flate
+		buffered := bufio.NewReader(res.Body)
+		if header, _ := buffered.Peek(2); isZlibHeader(header) {
+			zr, err := zlib.NewReader(buffered)
+			if err != nil {
+				return nil, err
+			}
+			defer zr.Close()
+			r = zr
+		} else {
+			fr := flate.NewReader(buffered)
+			defer fr.Close()
+			r = fr
+		}
+	case "br":
+		r = brotli.NewReader(res.Body)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", res.Header.Get("Content-Encoding"))
+	}
+
+	limit := c.MaxBodySize
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
 		return nil, err
 	}
+	if int64(len(body)) > limit {
+		return nil, ErrBodyTooLarge
+	}
+	return body, nil
+}
 
-	rss.Channel.Title = html.UnescapeString(rss.Channel.Title)
-	rss.Channel.Description = html.UnescapeString(rss.Channel.Description)
+// checkContentType rejects responses that are obviously not feeds (XML or
+// JSON Feed). Feeds are served with all sorts of wrong types, so anything
+// ambiguous is sniffed.
+func checkContentType(contentType string, body []byte) *ContentTypeError {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if contentType == "" || err != nil {
+		return nil
+	}
+	if strings.Contains(mediaType, "xml") || strings.HasSuffix(mediaType, "json") {
+		return nil
+	}
+	switch mediaType {
+	case "text/plain", "application/octet-stream":
+		return nil
+	}
+	if looksLikeXMLFeed(body) || isJSONFeed("", body) {
+		return nil
+	}
+	return &ContentTypeError{ContentType: mediaType}
+}
 
-	for i := range rss.Channel.Item {
-		rss.Channel.Item[i].Title = html.UnescapeString(rss.Channel.Item[i].Title)
-		rss.Channel.Item[i].Description = html.UnescapeString(rss.Channel.Item[i].Description)
+func looksLikeXMLFeed(body []byte) bool {
+	trimmed := bytes.TrimLeft(body, "\xef\xbb\xbf \t\r\n")
+	for _, prefix := range []string{"<?xml", "<rss", "<feed", "<rdf:RDF"} {
+		if bytes.HasPrefix(trimmed, []byte(prefix)) {
+			return true
+		}
 	}
+	return false
+}
 
-	return &rss, nil
+func isZlibHeader(b []byte) bool {
+	return len(b) == 2 && b[0]&0x0f == 8 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
 }
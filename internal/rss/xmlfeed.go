@@ -0,0 +1,160 @@
+package rss
+
+import (
+	"encoding/xml"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// structs
+
+// xmlFeed covers RSS 2.0 and RSS 1.0 (RDF). RDF puts the items next to the
+// channel instead of inside it, and dates and authors in Dublin Core.
+// Podcasts add their metadata in the itunes namespace.
+type xmlFeed struct {
+	Channel struct {
+		Title       string      `xml:"title"`
+		Link        string      `xml:"link"`
+		Description string      `xml:"description"`
+		ItunesImage itunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+		Item        []xmlItem   `xml:"item"`
+	} `xml:"channel"`
+	Item []xmlItem `xml:"item"` // RSS 1.0
+}
+
+type xmlItem struct {
+	RSSItem
+	DCDate    string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	DCCreator string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	DCSubject []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+	RDFAbout  string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+
+	ItunesAuthor   string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author"`
+	ItunesDuration string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ItunesImage    itunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// functions
+
+// parseXMLFeed decodes an RSS 2.0 or RDF feed into RSSFeed
+func parseXMLFeed(body []byte, contentType string) (*RSSFeed, error) {
+	decoder, err := newXMLDecoder(body, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	var feed xmlFeed
+	if err := decoder.Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	var rss RSSFeed
+	rss.Channel.Title = html.UnescapeString(feed.Channel.Title)
+	rss.Channel.Link = feed.Channel.Link
+	rss.Channel.Description = html.UnescapeString(feed.Channel.Description)
+	rss.Channel.Image = strings.TrimSpace(feed.Channel.ItunesImage.Href)
+
+	items := append(feed.Channel.Item, feed.Item...)
+	for _, item := range items {
+		out := item.normalize()
+		if out.Image == "" {
+			out.Image = rss.Channel.Image
+		}
+		rss.Channel.Item = append(rss.Channel.Item, out)
+	}
+	return &rss, nil
+}
+
+// helpers
+
+// normalize falls back to the Dublin Core and RDF fields, RSS 2.0 feeds often
+// use dc: as well, and unescapes text the way feeds tend to double encode it.
+func (item xmlItem) normalize() RSSItem {
+	out := item.RSSItem
+	if out.PubDate == "" {
+		out.PubDate = item.DCDate
+	}
+	if out.Author == "" {
+		out.Author = item.DCCreator
+	}
+	if out.Author == "" {
+		out.Author = item.ItunesAuthor
+	}
+	out.Duration = parseDuration(item.ItunesDuration)
+	out.Image = strings.TrimSpace(item.ItunesImage.Href)
+	if out.GUID == "" {
+		out.GUID = item.RDFAbout
+	}
+	out.Categories = cleanCategories(append(out.Categories, item.DCSubject...))
+
+	enclosures := out.Enclosures[:0]
+	for _, enclosure := range out.Enclosures {
+		if enclosure.URL != "" {
+			enclosures = append(enclosures, enclosure)
+		}
+	}
+	out.Enclosures = enclosures
+	out.Comments = strings.TrimSpace(out.Comments)
+
+	out.Title = html.UnescapeString(out.Title)
+	out.Description = html.UnescapeString(out.Description)
+	return out
+}
+
+// UnmarshalXML reads <enclosure url length type/>. Feeds put all sorts of
+// things in length, so it is parsed leniently instead of failing the feed.
+func (e *Enclosure) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		value := strings.TrimSpace(attr.Value)
+		switch attr.Name.Local {
+		case "url":
+			e.URL = value
+		case "type":
+			e.Type = value
+		case "length":
+			if length, err := strconv.ParseInt(value, 10, 64); err == nil && length > 0 {
+				e.Length = length
+			}
+		}
+	}
+	return d.Skip()
+}
+
+// parseDuration reads itunes:duration, which is either seconds or
+// [HH:]MM:SS. Anything else counts as unknown.
+func parseDuration(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	var seconds float64
+	for _, part := range strings.Split(value, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// cleanCategories trims categories and drops empty and repeated ones
+func cleanCategories(categories []string) []string {
+	seen := make(map[string]bool, len(categories))
+	out := make([]string, 0, len(categories))
+	for _, category := range categories {
+		category = strings.TrimSpace(html.UnescapeString(category))
+		if category == "" || seen[strings.ToLower(category)] {
+			continue
+		}
+		seen[strings.ToLower(category)] = true
+		out = append(out, category)
+	}
+	return out
+}
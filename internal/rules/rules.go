@@ -0,0 +1,194 @@
+// Package rules matches posts against the filtering rules of a user. A rule
+// looks at one field of a post, or all of them, for a keyword or a regular
+// expression, and names what to do with a match: hide the post, mark it
+// read or tag it.
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/curator4/gator/internal/database"
+	"github.com/curator4/gator/internal/htmltext"
+)
+
+// consts
+const (
+	FieldAny         = "any"
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldAuthor      = "author"
+	FieldCategory    = "category"
+)
+
+const (
+	MatchKeyword = "keyword"
+	MatchRegex   = "regex"
+)
+
+const (
+	ActionHide = "hide"
+	ActionRead = "read"
+	ActionTag  = "tag"
+)
+
+var (
+	Fields  = []string{FieldAny, FieldTitle, FieldDescription, FieldAuthor, FieldCategory}
+	Actions = []string{ActionHide, ActionRead, ActionTag}
+)
+
+// structs
+
+// Post is what rules look at. Description is html, as stored.
+type Post struct {
+	Title       string
+	Description string
+	Author      string
+	Categories  []string
+}
+
+// Matcher looks for a keyword or a regular expression in one field of a
+// post, or all of them. Watches use it on their own.
+type Matcher struct {
+	field     string
+	matchType string
+	pattern   string
+	re        *regexp.Regexp
+	keyword   string
+}
+
+// Rule is a stored rule, ready to match.
+type Rule struct {
+	database.Rule
+	*Matcher
+}
+
+// functions
+
+// NewMatcher checks a field, match type and pattern and prepares them for
+// matching.
+func NewMatcher(field, matchType, pattern string) (*Matcher, error) {
+	if !slices.Contains(Fields, field) {
+		return nil, fmt.Errorf("unknown field %q, use %s", field, strings.Join(Fields, ", "))
+	}
+
+	m := &Matcher{field: field, matchType: matchType, pattern: pattern}
+	switch matchType {
+	case MatchKeyword:
+		m.keyword = strings.ToLower(strings.TrimSpace(pattern))
+		if m.keyword == "" {
+			return nil, fmt.Errorf("empty keyword")
+		}
+	case MatchRegex:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		m.re = re
+	default:
+		return nil, fmt.Errorf("unknown match type %q, use keyword or regex", matchType)
+	}
+	return m, nil
+}
+
+// Compile checks a stored or new rule and prepares it for matching.
+func Compile(r database.Rule) (*Rule, error) {
+	if !slices.Contains(Actions, r.Action) {
+		return nil, fmt.Errorf("unknown action %q, use %s", r.Action, strings.Join(Actions, ", "))
+	}
+	if r.Action == ActionTag && strings.TrimSpace(r.Tag.String) == "" {
+		return nil, fmt.Errorf("the tag action needs a tag name")
+	}
+
+	m, err := NewMatcher(r.Field, r.MatchType, r.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &Rule{Rule: r, Matcher: m}, nil
+}
+
+// CompileAll compiles rules, skipping the ones that no longer compile,
+// e.g. after a change of the regex syntax.
+func CompileAll(rules []database.Rule) []*Rule {
+	compiled := make([]*Rule, 0, len(rules))
+	for _, r := range rules {
+		if c, err := Compile(r); err == nil {
+			compiled = append(compiled, c)
+		}
+	}
+	return compiled
+}
+
+// Match reports whether post matches. Keywords match anywhere in
+// the text, ignoring case, but must equal a whole category. Descriptions
+// are matched as plain text, without their markup.
+func (m *Matcher) Match(post Post) bool {
+	switch m.field {
+	case FieldTitle:
+		return m.matchText(post.Title)
+	case FieldDescription:
+		return m.matchText(plainText(post.Description))
+	case FieldAuthor:
+		return m.matchText(post.Author)
+	case FieldCategory:
+		return m.matchCategories(post.Categories)
+	}
+	return m.matchText(post.Title) ||
+		m.matchText(plainText(post.Description)) ||
+		m.matchText(post.Author) ||
+		m.matchCategories(post.Categories)
+}
+
+func (m *Matcher) matchText(text string) bool {
+	if text == "" {
+		return false
+	}
+	if m.re != nil {
+		return m.re.MatchString(text)
+	}
+	return strings.Contains(strings.ToLower(text), m.keyword)
+}
+
+func (m *Matcher) matchCategories(categories []string) bool {
+	for _, category := range categories {
+		if m.re != nil && m.re.MatchString(category) {
+			return true
+		}
+		if m.re == nil && strings.EqualFold(strings.TrimSpace(category), m.keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Matcher) String() string {
+	return Describe(m.field, m.matchType, m.pattern)
+}
+
+// String describes the rule in one line, e.g. `title ~ "sponsored" → hide`.
+func (r *Rule) String() string {
+	action := r.Action
+	if r.Action == ActionTag {
+		action += " " + r.Tag.String
+	}
+	return fmt.Sprintf("%s → %s", Describe(r.Field, r.MatchType, r.Pattern), action)
+}
+
+// Describe says what a matcher looks for, e.g. `title ~ "sponsored"`, it
+// doesn't need to compile.
+func Describe(field, matchType, pattern string) string {
+	if matchType == MatchRegex {
+		return fmt.Sprintf("%s ~ /%s/", field, pattern)
+	}
+	return fmt.Sprintf("%s ~ %q", field, pattern)
+}
+
+// helpers
+func plainText(s string) string {
+	if s == "" {
+		return ""
+	}
+	return htmltext.Render(s, htmltext.Options{Links: htmltext.LinksNone})
+}
@@ -0,0 +1,567 @@
+package server
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/curator4/gator/internal/auth"
+	"github.com/curator4/gator/internal/database"
+	"github.com/curator4/gator/internal/rss"
+	"github.com/curator4/gator/internal/urlcanon"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// consts
+const (
+	defaultAPILimit = 20
+	maxAPILimit     = 100
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// structs
+type apiError struct {
+	Error apiErrorBody `json:"error"`
+}
+
+type apiErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type apiUser struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type apiNewUser struct {
+	apiUser
+	APIKey string `json:"api_key"`
+}
+
+type apiFeed struct {
+	ID             uuid.UUID `json:"id"`
+	Name           string    `json:"name"`
+	URL            string    `json:"url"`
+	AddedBy        string    `json:"added_by"`
+	HasCredentials bool      `json:"has_credentials"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type apiFollow struct {
+	ID        uuid.UUID `json:"id"`
+	FeedID    uuid.UUID `json:"feed_id"`
+	FeedName  string    `json:"feed_name"`
+	FeedURL   string    `json:"feed_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type apiPost struct {
+	ID          uuid.UUID      `json:"id"`
+	Title       string         `json:"title"`
+	URL         string         `json:"url"`
+	Description *string        `json:"description"`
+	Content     *string        `json:"content"`
+	FullContent *string        `json:"full_content"`
+	Author      *string        `json:"author"`
+	CommentsURL *string        `json:"comments_url"`
+	Categories  []string       `json:"categories"`
+	Enclosures  []apiEnclosure `json:"enclosures"`
+	PublishedAt time.Time      `json:"published_at"`
+	FeedID      uuid.UUID      `json:"feed_id"`
+	FeedName    string         `json:"feed_name"`
+}
+
+type apiEnclosure struct {
+	URL    string  `json:"url"`
+	Length *int64  `json:"length"`
+	Type   *string `json:"type"`
+}
+
+// functions
+func (s *Server) registerAPI(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPISpec)
+	})
+
+	mux.HandleFunc("GET /api/v1/users", s.withAPIUser(s.apiListUsers))
+	mux.HandleFunc("POST /api/v1/users", s.withAPIUser(s.apiCreateUser))
+	mux.HandleFunc("DELETE /api/v1/users/{id}", s.withAPIUser(s.apiDeleteUser))
+
+	mux.HandleFunc("GET /api/v1/feeds", s.withAPIUser(s.apiListFeeds))
+	mux.HandleFunc("POST /api/v1/feeds", s.withAPIUser(s.apiCreateFeed))
+	mux.HandleFunc("GET /api/v1/feeds/{id}", s.withAPIUser(s.apiGetFeed))
+	mux.HandleFunc("DELETE /api/v1/feeds/{id}", s.withAPIUser(s.apiDeleteFeed))
+
+	mux.HandleFunc("GET /api/v1/follows", s.withAPIUser(s.apiListFollows))
+	mux.HandleFunc("POST /api/v1/follows", s.withAPIUser(s.apiCreateFollow))
+	mux.HandleFunc("DELETE /api/v1/follows/{feed_id}", s.withAPIUser(s.apiDeleteFollow))
+
+	mux.HandleFunc("GET /api/v1/posts", s.withAPIUser(s.apiListPosts))
+
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", "no such endpoint")
+	})
+}
+
+// users
+func (s *Server) apiListUsers(w http.ResponseWriter, r *http.Request, user database.User) {
+	users, err := s.db.GetUsers(r.Context())
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	out := make([]apiUser, 0, len(users))
+	for _, u := range users {
+		out = append(out, toAPIUser(u))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) apiCreateUser(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Name) == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "name is required")
+		return
+	}
+
+	key, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	current_time := time.Now()
+	created, err := s.db.CreateUser(r.Context(), database.CreateUserParams{
+		ID:         uuid.New(),
+		CreatedAt:  current_time,
+		UpdatedAt:  current_time,
+		Name:       body.Name,
+		ApiKeyHash: sql.NullString{String: hash, Valid: true},
+	})
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	// the only time the key is shown
+	writeJSON(w, http.StatusCreated, apiNewUser{apiUser: toAPIUser(created), APIKey: key})
+}
+
+func (s *Server) apiDeleteUser(w http.ResponseWriter, r *http.Request, user database.User) {
+	id, ok := pathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	if id != user.ID {
+		writeError(w, http.StatusForbidden, "forbidden", "users can only delete themselves")
+		return
+	}
+	if err := s.db.DeleteUser(r.Context(), id); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// feeds
+func (s *Server) apiListFeeds(w http.ResponseWriter, r *http.Request, user database.User) {
+	feeds, err := s.db.GetFeeds(r.Context())
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	out := make([]apiFeed, 0, len(feeds))
+	for _, feed := range feeds {
+		out = append(out, apiFeed{
+			ID:             feed.ID,
+			Name:           feed.Name,
+			URL:            rss.RedactURL(feed.Url),
+			AddedBy:        feed.Username,
+			HasCredentials: feed.AuthType.Valid,
+			CreatedAt:      feed.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) apiGetFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	id, ok := pathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	feed, err := s.db.GetFeedByID(r.Context(), id)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	owner, err := s.db.GetUserByID(r.Context(), feed.UserID)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	_, err = s.db.GetFeedCredential(r.Context(), feed.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeDBError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, apiFeed{
+		ID:             feed.ID,
+		Name:           feed.Name,
+		URL:            rss.RedactURL(feed.Url),
+		AddedBy:        owner.Name,
+		HasCredentials: err == nil,
+		CreatedAt:      feed.CreatedAt,
+	})
+}
+
+// apiCreateFeed adds a feed and follows it, like the addfeed command
+func (s *Server) apiCreateFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.Name == "" || body.URL == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "name and url are required")
+		return
+	}
+	u, err := url.Parse(body.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		writeError(w, http.StatusBadRequest, "invalid_request", "url must be an http or https url")
+		return
+	}
+	if u.User != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "credentials don't belong in the url, set them with gator feedauth")
+		return
+	}
+	canonical, err := urlcanon.Canonicalize(body.URL)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "url must be an http or https url")
+		return
+	}
+	if _, err := s.db.GetFeedByURLs(r.Context(), urlcanon.Variants(canonical)); err == nil {
+		writeError(w, http.StatusConflict, "conflict", "feed already exists, follow it instead")
+		return
+	}
+
+	current_time := time.Now()
+	feed, err := s.db.CreateFeed(r.Context(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: current_time,
+		UpdatedAt: current_time,
+		Name:      body.Name,
+		Url:       canonical,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	_, err = s.db.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: current_time,
+		UpdatedAt: current_time,
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, apiFeed{
+		ID:        feed.ID,
+		Name:      feed.Name,
+		URL:       feed.Url,
+		AddedBy:   user.Name,
+		CreatedAt: feed.CreatedAt,
+	})
+}
+
+func (s *Server) apiDeleteFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	id, ok := pathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	feed, err := s.db.GetFeedByID(r.Context(), id)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if feed.UserID != user.ID {
+		writeError(w, http.StatusForbidden, "forbidden", "only the user who added a feed can delete it")
+		return
+	}
+	if err := s.db.DeleteFeed(r.Context(), id); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// follows
+func (s *Server) apiListFollows(w http.ResponseWriter, r *http.Request, user database.User) {
+	follows, err := s.db.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	out := make([]apiFollow, 0, len(follows))
+	for _, follow := range follows {
+		out = append(out, apiFollow{
+			ID:        follow.ID,
+			FeedID:    follow.FeedID,
+			FeedName:  follow.FeedName,
+			FeedURL:   rss.RedactURL(follow.FeedUrl),
+			CreatedAt: follow.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) apiCreateFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		FeedID *uuid.UUID `json:"feed_id"`
+		URL    string     `json:"url"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	var feed database.Feed
+	var err error
+	switch {
+	case body.FeedID != nil:
+		feed, err = s.db.GetFeedByID(r.Context(), *body.FeedID)
+	case body.URL != "":
+		feed, err = s.db.GetFeedByURLs(r.Context(), urlcanon.Variants(body.URL))
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_request", "feed_id or url is required")
+		return
+	}
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	current_time := time.Now()
+	follow, err := s.db.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: current_time,
+		UpdatedAt: current_time,
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiFollow{
+		ID:        follow.ID,
+		FeedID:    feed.ID,
+		FeedName:  follow.FeedName,
+		FeedURL:   rss.RedactURL(feed.Url),
+		CreatedAt: follow.CreatedAt,
+	})
+}
+
+func (s *Server) apiDeleteFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedID, ok := pathUUID(w, r, "feed_id")
+	if !ok {
+		return
+	}
+	err := s.db.DeleteFeedFollow(r.Context(), database.DeleteFeedFollowParams{
+		UserID: user.ID,
+		FeedID: feedID,
+	})
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// posts
+func (s *Server) apiListPosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	query := r.URL.Query()
+	params := database.GetUserPostsParams{
+		UserID: user.ID,
+		Limit:  defaultAPILimit,
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxAPILimit {
+			writeError(w, http.StatusBadRequest, "invalid_request", "limit must be between 1 and 100")
+			return
+		}
+		params.Limit = int32(limit)
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, "invalid_request", "offset must be a non-negative number")
+			return
+		}
+		params.Offset = int32(offset)
+	}
+	if v := query.Get("feed_id"); v != "" {
+		feedID, err := uuid.Parse(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "feed_id must be a uuid")
+			return
+		}
+		params.FeedID = uuid.NullUUID{UUID: feedID, Valid: true}
+	}
+	if v := query.Get("since"); v != "" {
+		since, err := parseSince(v, time.Now())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "since must be an RFC 3339 time or a duration like 24h")
+			return
+		}
+		params.Since = sql.NullTime{Time: since, Valid: true}
+	}
+	if v := query.Get("q"); v != "" {
+		params.Search = sql.NullString{String: v, Valid: true}
+	}
+	if v := query.Get("tag"); v != "" {
+		params.Category = sql.NullString{String: v, Valid: true}
+	}
+
+	posts, err := s.db.GetUserPosts(r.Context(), params)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	enclosures, err := s.db.GetPostEnclosures(r.Context(), postIDs(posts))
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	byPost := make(map[uuid.UUID][]apiEnclosure)
+	for _, enclosure := range enclosures {
+		e := apiEnclosure{URL: enclosure.Url}
+		if enclosure.Length.Valid {
+			e.Length = &enclosure.Length.Int64
+		}
+		if enclosure.MimeType.Valid {
+			e.Type = &enclosure.MimeType.String
+		}
+		byPost[enclosure.PostID] = append(byPost[enclosure.PostID], e)
+	}
+
+	out := make([]apiPost, 0, len(posts))
+	for _, post := range posts {
+		p := apiPost{
+			ID:          post.ID,
+			Title:       post.Title,
+			URL:         post.Url,
+			Categories:  post.Categories,
+			Enclosures:  byPost[post.ID],
+			PublishedAt: post.PublishedAt,
+			FeedID:      post.FeedID,
+			FeedName:    post.FeedName,
+		}
+		if p.Categories == nil {
+			p.Categories = []string{}
+		}
+		if p.Enclosures == nil {
+			p.Enclosures = []apiEnclosure{}
+		}
+		if post.Description.Valid {
+			p.Description = &post.Description.String
+		}
+		if post.Content.Valid {
+			p.Content = &post.Content.String
+		}
+		if post.FullContent.Valid {
+			p.FullContent = &post.FullContent.String
+		}
+		if post.Author.Valid {
+			p.Author = &post.Author.String
+		}
+		if post.CommentsUrl.Valid {
+			p.CommentsURL = &post.CommentsUrl.String
+		}
+		out = append(out, p)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// helpers
+func toAPIUser(u database.User) apiUser {
+	return apiUser{ID: u.ID, Name: u.Name, CreatedAt: u.CreatedAt}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("writing response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, apiError{Error: apiErrorBody{Code: code, Message: message}})
+}
+
+// writeDBError maps query errors onto http statuses
+func writeDBError(w http.ResponseWriter, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "not_found", "not found")
+		return
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		writeError(w, http.StatusConflict, "conflict", "already exists")
+		return
+	}
+	log.Printf("api error: %v", err)
+	writeError(w, http.StatusInternalServerError, "internal", "internal server error")
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "invalid json body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func pathUUID(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue(name))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", name+" must be a uuid")
+		return uuid.UUID{}, false
+	}
+	return id, true
+}
+
+// parseSince accepts an absolute RFC 3339 time or a duration back from now
+func parseSince(v string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
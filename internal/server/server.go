@@ -0,0 +1,351 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/curator4/gator/internal/auth"
+	"github.com/curator4/gator/internal/database"
+	"github.com/curator4/gator/internal/export"
+	"github.com/curator4/gator/internal/htmltext"
+	"github.com/curator4/gator/internal/rss"
+	"github.com/google/uuid"
+)
+
+// consts
+const (
+	defaultPageSize = 20
+	keyCookie       = "gator_key"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// structs
+
+// Server renders the gator database as a small web reader.
+type Server struct {
+	db       *database.Queries
+	pageSize int
+	pages    map[string]*template.Template
+}
+
+type loginPage struct {
+	User  string
+	Error string
+}
+
+type postsPage struct {
+	User     string
+	Posts    []database.GetUserPostsRow
+	Tag      string
+	Page     int
+	PrevPage int
+	NextPage int
+}
+
+type feedsPage struct {
+	User  string
+	Feeds []database.GetFeedsRow
+}
+
+type followingPage struct {
+	User    string
+	Follows []database.GetFeedFollowsForUserRow
+}
+
+// functions
+func New(db *database.Queries) (*Server, error) {
+	funcs := template.FuncMap{
+		"plaintext": plaintext,
+		"redact":    rss.RedactURL,
+	}
+
+	pages := make(map[string]*template.Template)
+	for _, name := range []string{"login", "posts", "feeds", "following"} {
+		t, err := template.New("layout.html").Funcs(funcs).ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html")
+		if err != nil {
+			return nil, err
+		}
+		pages[name] = t
+	}
+
+	return &Server{
+		db:       db,
+		pageSize: defaultPageSize,
+		pages:    pages,
+	}, nil
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.withUser(s.handlePosts))
+	mux.HandleFunc("GET /feeds", s.withUser(s.handleFeeds))
+	mux.HandleFunc("GET /following", s.withUser(s.handleFollowing))
+	mux.HandleFunc("GET /login", s.handleLoginForm)
+	mux.HandleFunc("POST /login", s.handleLogin)
+	mux.HandleFunc("POST /logout", s.handleLogout)
+	mux.HandleFunc("GET /export/{format}", s.withFeedReaderUser(s.handleExport))
+	s.registerAPI(mux)
+	return mux
+}
+
+// withUser resolves the user a page is rendered for from the api key header
+// or the login cookie, like middlewareLoggedIn does for the cli
+func (s *Server) withUser(handler func(http.ResponseWriter, *http.Request, database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := requestAPIKey(r)
+		if key == "" {
+			if cookie, err := r.Cookie(keyCookie); err == nil {
+				key = cookie.Value
+			}
+		}
+		user, err := s.userForKey(r.Context(), key)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+// withFeedReaderUser also takes the key from a ?key= parameter, since feed
+// readers can't send headers. It is only used for read-only routes.
+func (s *Server) withFeedReaderUser(handler func(http.ResponseWriter, *http.Request, database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if key := r.URL.Query().Get("key"); key != "" {
+			user, err := s.userForKey(r.Context(), key)
+			if err != nil {
+				http.Error(w, "invalid api key", http.StatusUnauthorized)
+				return
+			}
+			handler(w, r, user)
+			return
+		}
+		s.withUser(handler)(w, r)
+	}
+}
+
+// withAPIUser only accepts the key in a header, so a browser's login cookie
+// can't be used to make api calls from other sites
+func (s *Server) withAPIUser(handler func(http.ResponseWriter, *http.Request, database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := requestAPIKey(r)
+		if key == "" {
+			writeError(w, http.StatusUnauthorized, "unauthorized", "an api key is required, send it as Authorization: Bearer <key>")
+			return
+		}
+		user, err := s.userForKey(r.Context(), key)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				log.Printf("api key lookup: %v", err)
+			}
+			writeError(w, http.StatusUnauthorized, "unauthorized", "invalid api key")
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+// handlers
+func (s *Server) handlePosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		var err error
+		page, err = strconv.Atoi(p)
+		if err != nil || page < 1 {
+			http.Error(w, "invalid page", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// one extra row tells us whether there is a next page
+	params := database.GetUserPostsParams{
+		UserID: user.ID,
+		Limit:  int32(s.pageSize + 1),
+		Offset: int32((page - 1) * s.pageSize),
+	}
+	tag := r.URL.Query().Get("tag")
+	if tag != "" {
+		params.Category = sql.NullString{String: tag, Valid: true}
+	}
+	posts, err := s.db.GetUserPosts(r.Context(), params)
+	if err != nil {
+		s.serverError(w, err)
+		return
+	}
+
+	data := postsPage{
+		User:     user.Name,
+		Posts:    posts,
+		Tag:      tag,
+		Page:     page,
+		PrevPage: page - 1,
+	}
+	if len(posts) > s.pageSize {
+		data.Posts = posts[:s.pageSize]
+		data.NextPage = page + 1
+	}
+	s.render(w, "posts", data)
+}
+
+func (s *Server) handleFeeds(w http.ResponseWriter, r *http.Request, user database.User) {
+	feeds, err := s.db.GetFeeds(r.Context())
+	if err != nil {
+		s.serverError(w, err)
+		return
+	}
+	s.render(w, "feeds", feedsPage{User: user.Name, Feeds: feeds})
+}
+
+func (s *Server) handleFollowing(w http.ResponseWriter, r *http.Request, user database.User) {
+	follows, err := s.db.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		s.serverError(w, err)
+		return
+	}
+	s.render(w, "following", followingPage{User: user.Name, Follows: follows})
+}
+
+// handleExport re-publishes the user's timeline as rss, atom or json feed
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request, user database.User) {
+	format := r.PathValue("format")
+	switch format {
+	case export.FormatRSS, export.FormatAtom, export.FormatJSONFeed:
+	default:
+		http.Error(w, "unknown format, use rss, atom or jsonfeed", http.StatusNotFound)
+		return
+	}
+
+	params := database.GetUserPostsParams{
+		UserID: user.ID,
+		Limit:  50,
+	}
+	if v := r.URL.Query().Get("feed_id"); v != "" {
+		feedID, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "feed_id must be a uuid", http.StatusBadRequest)
+			return
+		}
+		params.FeedID = uuid.NullUUID{UUID: feedID, Valid: true}
+	}
+	if v := r.URL.Query().Get("category"); v != "" {
+		params.Category = sql.NullString{String: v, Valid: true}
+	}
+	posts, err := s.db.GetUserPosts(r.Context(), params)
+	if err != nil {
+		s.serverError(w, err)
+		return
+	}
+	enclosures, err := s.db.GetPostEnclosures(r.Context(), postIDs(posts))
+	if err != nil {
+		s.serverError(w, err)
+		return
+	}
+
+	base := baseURL(r)
+	feed := export.Feed{
+		ID:          "urn:uuid:" + user.ID.String(),
+		Title:       user.Name + "'s gator timeline",
+		Link:        base + "/",
+		SelfURL:     base + r.URL.Path,
+		Description: "Posts from the feeds " + user.Name + " follows",
+		Updated:     time.Now(),
+		Items:       export.PostItems(posts, enclosures),
+	}
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	if err := export.Write(w, format, feed); err != nil {
+		log.Printf("export: %v", err)
+	}
+}
+
+func (s *Server) handleLoginForm(w http.ResponseWriter, r *http.Request) {
+	s.render(w, "login", loginPage{})
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimSpace(r.PostFormValue("key"))
+	if _, err := s.userForKey(r.Context(), key); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		s.render(w, "login", loginPage{Error: "invalid api key"})
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     keyCookie,
+		Value:    key,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     keyCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// helpers
+func (s *Server) userForKey(ctx context.Context, key string) (database.User, error) {
+	if key == "" {
+		return database.User{}, sql.ErrNoRows
+	}
+	hash := sql.NullString{String: auth.HashToken(key), Valid: true}
+	return s.db.GetUserByAPIKey(ctx, hash)
+}
+
+func postIDs(posts []database.GetUserPostsRow) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	return ids
+}
+
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func requestAPIKey(r *http.Request) string {
+	if key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(key)
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+func (s *Server) render(w http.ResponseWriter, page string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.pages[page].Execute(w, data); err != nil {
+		log.Printf("rendering %s: %v", page, err)
+	}
+}
+
+func (s *Server) serverError(w http.ResponseWriter, err error) {
+	log.Printf("server error: %v", err)
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
+
+// plaintext strips the feed's markup, descriptions are untrusted html
+func plaintext(s string) string {
+	return htmltext.Render(s, htmltext.Options{MaxLength: 600, Links: htmltext.LinksNone})
+}
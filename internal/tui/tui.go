@@ -0,0 +1,566 @@
+// Package tui is the full screen reader behind `gator tui`: sources on the
+// left (all, unread, saved, followed feeds, categories), the posts of the
+// selected source in the middle and the selected post on the right.
+package tui
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/curator4/gator/internal/database"
+	"github.com/curator4/gator/internal/htmltext"
+	"github.com/google/uuid"
+)
+
+// consts
+const (
+	DefaultRefresh = 15 * time.Second
+
+	postLimit     = 200
+	categoryLimit = 30
+	sourcesWidth  = 26
+)
+
+const (
+	paneSources = iota
+	panePosts
+	paneReader
+	paneCount
+)
+
+type sourceKind int
+
+const (
+	sourceAll sourceKind = iota
+	sourceUnread
+	sourceSaved
+	sourceFeed
+	sourceCategory
+)
+
+// styles
+var (
+	paneStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8"))
+	focusedStyle = paneStyle.BorderForeground(lipgloss.Color("12"))
+	cursorStyle  = lipgloss.NewStyle().Reverse(true)
+	dimStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	headingStyle = lipgloss.NewStyle().Bold(true)
+	statusStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+)
+
+const help = "tab switch pane · j/k move · enter read · m read/unread · s save · o open in browser · r refresh · q quit"
+
+// structs
+
+// Options configures Run. Open shows a url in the browser.
+type Options struct {
+	Open    func(url string) error
+	ShortID func(id uuid.UUID) string // the id shown for a post, as the cli shows it
+	Refresh time.Duration             // how often to look for new posts, DefaultRefresh if 0
+}
+
+type source struct {
+	label    string
+	kind     sourceKind
+	feedID   uuid.UUID
+	category string
+}
+
+type model struct {
+	db   *database.Queries
+	user database.User
+	opts Options
+
+	width, height int
+	focus         int
+
+	sources      []source
+	sourceCursor int
+
+	posts      []database.GetUserPostsRow
+	postCursor int
+	postOffset int
+
+	readerLines  []string
+	readerScroll int
+
+	latest time.Time
+	status string
+}
+
+// messages
+type sourcesMsg []source
+
+type postsMsg struct {
+	posts  []database.GetUserPostsRow
+	keepID uuid.UUID
+}
+
+type latestMsg time.Time
+
+type tickMsg struct{}
+
+type statusMsg string
+
+type errMsg struct{ err error }
+
+// functions
+
+// Run starts the reader for user and blocks until it is closed.
+func Run(db *database.Queries, user database.User, opts Options) error {
+	if opts.Refresh <= 0 {
+		opts.Refresh = DefaultRefresh
+	}
+	m := &model{db: db, user: user, opts: opts, focus: panePosts}
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+func (m *model) Init() tea.Cmd {
+	return tea.Batch(m.loadSources(), m.loadPosts(uuid.Nil), m.checkLatest(), m.tick())
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.renderReader()
+		return m, nil
+
+	case sourcesMsg:
+		m.sources = msg
+		m.sourceCursor = min(m.sourceCursor, len(m.sources)-1)
+		return m, nil
+
+	case postsMsg:
+		m.posts = msg.posts
+		m.postCursor = 0
+		for i, post := range m.posts {
+			if post.ID == msg.keepID {
+				m.postCursor = i
+				break
+			}
+		}
+		m.postOffset = 0
+		m.readerScroll = 0
+		m.renderReader()
+		return m, nil
+
+	case latestMsg:
+		latest := time.Time(msg)
+		if m.latest.IsZero() {
+			m.latest = latest
+			return m, nil
+		}
+		if latest.After(m.latest) {
+			m.latest = latest
+			m.status = "new posts at " + time.Now().Format("15:04")
+			return m, tea.Batch(m.loadSources(), m.loadPosts(m.selectedID()))
+		}
+		return m, nil
+
+	case tickMsg:
+		return m, tea.Batch(m.checkLatest(), m.tick())
+
+	case statusMsg:
+		m.status = string(msg)
+		return m, nil
+
+	case errMsg:
+		m.status = "error: " + msg.err.Error()
+		return m, nil
+
+	case tea.KeyMsg:
+		return m, m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m *model) handleKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return tea.Quit
+	case "tab", "l", "right":
+		m.focus = (m.focus + 1) % paneCount
+		return nil
+	case "shift+tab", "h", "left":
+		m.focus = (m.focus + paneCount - 1) % paneCount
+		return nil
+	case "r":
+		m.status = "refreshed"
+		return tea.Batch(m.loadSources(), m.loadPosts(m.selectedID()))
+	case "m":
+		return m.toggleRead()
+	case "s":
+		return m.toggleSaved()
+	case "o":
+		post, ok := m.selected()
+		if !ok {
+			return nil
+		}
+		if err := m.opts.Open(post.Url); err != nil {
+			m.status = "error: " + err.Error()
+			return nil
+		}
+		m.status = "opened " + post.Url
+		return m.markRead(true)
+	}
+
+	switch m.focus {
+	case paneSources:
+		return m.moveSources(msg.String())
+	case panePosts:
+		return m.movePosts(msg.String())
+	case paneReader:
+		m.scrollReader(msg.String())
+	}
+	return nil
+}
+
+func (m *model) moveSources(key string) tea.Cmd {
+	previous := m.sourceCursor
+	switch key {
+	case "j", "down":
+		m.sourceCursor++
+	case "k", "up":
+		m.sourceCursor--
+	case "g", "home":
+		m.sourceCursor = 0
+	case "G", "end":
+		m.sourceCursor = len(m.sources) - 1
+	case "enter":
+		m.focus = panePosts
+		return nil
+	}
+	m.sourceCursor = max(0, min(m.sourceCursor, len(m.sources)-1))
+	if m.sourceCursor != previous {
+		return m.loadPosts(uuid.Nil)
+	}
+	return nil
+}
+
+func (m *model) movePosts(key string) tea.Cmd {
+	previous := m.postCursor
+	switch key {
+	case "j", "down":
+		m.postCursor++
+	case "k", "up":
+		m.postCursor--
+	case "pgdown", "ctrl+d":
+		m.postCursor += m.listHeight() / 2
+	case "pgup", "ctrl+u":
+		m.postCursor -= m.listHeight() / 2
+	case "g", "home":
+		m.postCursor = 0
+	case "G", "end":
+		m.postCursor = len(m.posts) - 1
+	case "enter":
+		m.focus = paneReader
+		return m.markRead(true)
+	}
+	m.postCursor = max(0, min(m.postCursor, len(m.posts)-1))
+	if m.postCursor != previous {
+		m.readerScroll = 0
+		m.renderReader()
+	}
+	return nil
+}
+
+func (m *model) scrollReader(key string) {
+	page := max(1, m.listHeight()-1)
+	switch key {
+	case "j", "down":
+		m.readerScroll++
+	case "k", "up":
+		m.readerScroll--
+	case " ", "pgdown", "ctrl+d":
+		m.readerScroll += page
+	case "pgup", "ctrl+u":
+		m.readerScroll -= page
+	case "g", "home":
+		m.readerScroll = 0
+	case "G", "end":
+		m.readerScroll = len(m.readerLines)
+	case "enter":
+		m.focus = panePosts
+	}
+	m.readerScroll = max(0, min(m.readerScroll, len(m.readerLines)-m.listHeight()))
+}
+
+// actions
+
+func (m *model) toggleRead() tea.Cmd {
+	post, ok := m.selected()
+	if !ok {
+		return nil
+	}
+	return m.markRead(!post.ReadAt.Valid)
+}
+
+func (m *model) markRead(read bool) tea.Cmd {
+	if m.postCursor >= len(m.posts) {
+		return nil
+	}
+	post := &m.posts[m.postCursor]
+	if post.ReadAt.Valid == read {
+		return nil
+	}
+	post.ReadAt = sql.NullTime{Time: time.Now(), Valid: read}
+	params := database.SetPostReadParams{UserID: m.user.ID, PostID: post.ID, ReadAt: post.ReadAt}
+	return func() tea.Msg {
+		if err := m.db.SetPostRead(context.Background(), params); err != nil {
+			return errMsg{err}
+		}
+		return nil
+	}
+}
+
+func (m *model) toggleSaved() tea.Cmd {
+	if m.postCursor >= len(m.posts) {
+		return nil
+	}
+	post := &m.posts[m.postCursor]
+	post.SavedAt = sql.NullTime{Time: time.Now(), Valid: !post.SavedAt.Valid}
+	params := database.SetPostSavedParams{UserID: m.user.ID, PostID: post.ID, SavedAt: post.SavedAt}
+	saved := post.SavedAt.Valid
+	return func() tea.Msg {
+		if err := m.db.SetPostSaved(context.Background(), params); err != nil {
+			return errMsg{err}
+		}
+		if saved {
+			return statusMsg("saved")
+		}
+		return statusMsg("removed from saved")
+	}
+}
+
+// commands
+
+func (m *model) loadSources() tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		sources := []source{
+			{label: "All posts", kind: sourceAll},
+			{label: "Unread", kind: sourceUnread},
+			{label: "Saved", kind: sourceSaved},
+		}
+		follows, err := m.db.GetFeedFollowsForUser(ctx, m.user.ID)
+		if err != nil {
+			return errMsg{err}
+		}
+		for _, follow := range follows {
+			sources = append(sources, source{label: follow.FeedName, kind: sourceFeed, feedID: follow.FeedID})
+		}
+		categories, err := m.db.GetUserCategories(ctx, database.GetUserCategoriesParams{UserID: m.user.ID, Limit: categoryLimit})
+		if err != nil {
+			return errMsg{err}
+		}
+		for _, category := range categories {
+			label := fmt.Sprintf("#%s (%d)", category.Name, category.Posts)
+			sources = append(sources, source{label: label, kind: sourceCategory, category: category.Name})
+		}
+		return sourcesMsg(sources)
+	}
+}
+
+func (m *model) loadPosts(keepID uuid.UUID) tea.Cmd {
+	params := database.GetUserPostsParams{UserID: m.user.ID, Limit: postLimit}
+	if m.sourceCursor < len(m.sources) {
+		src := m.sources[m.sourceCursor]
+		switch src.kind {
+		case sourceUnread:
+			params.UnreadOnly = true
+		case sourceSaved:
+			params.SavedOnly = true
+		case sourceFeed:
+			params.FeedID = uuid.NullUUID{UUID: src.feedID, Valid: true}
+		case sourceCategory:
+			params.Category = sql.NullString{String: src.category, Valid: true}
+		}
+	}
+	return func() tea.Msg {
+		posts, err := m.db.GetUserPosts(context.Background(), params)
+		if err != nil {
+			return errMsg{err}
+		}
+		return postsMsg{posts: posts, keepID: keepID}
+	}
+}
+
+func (m *model) checkLatest() tea.Cmd {
+	return func() tea.Msg {
+		latest, err := m.db.GetUserLatestPostTime(context.Background(), m.user.ID)
+		if err != nil {
+			return errMsg{err}
+		}
+		return latestMsg(latest)
+	}
+}
+
+func (m *model) tick() tea.Cmd {
+	return tea.Tick(m.opts.Refresh, func(time.Time) tea.Msg { return tickMsg{} })
+}
+
+// view
+
+func (m *model) View() string {
+	if m.width == 0 {
+		return "loading..."
+	}
+
+	height := m.listHeight()
+	postsWidth := max(20, (m.width-sourcesWidth)*2/5)
+	readerWidth := max(20, m.width-sourcesWidth-postsWidth)
+
+	panes := lipgloss.JoinHorizontal(lipgloss.Top,
+		m.pane(paneSources, sourcesWidth, height, m.sourceLines(sourcesWidth-2, height)),
+		m.pane(panePosts, postsWidth, height, m.postLines(postsWidth-2, height)),
+		m.pane(paneReader, readerWidth, height, m.visibleReaderLines(height)),
+	)
+
+	status := help
+	if m.status != "" {
+		status = m.status + " · " + help
+	}
+	return panes + "\n" + statusStyle.Render(ansi.Truncate(status, m.width, "…"))
+}
+
+func (m *model) pane(id, width, height int, lines []string) string {
+	style := paneStyle
+	if m.focus == id {
+		style = focusedStyle
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return style.Width(width - 2).Height(height).MaxHeight(height + 2).Render(strings.Join(lines, "\n"))
+}
+
+func (m *model) sourceLines(width, height int) []string {
+	start := max(0, m.sourceCursor-height+1)
+	var lines []string
+	for i := start; i < len(m.sources) && len(lines) < height; i++ {
+		line := ansi.Truncate(m.sources[i].label, width, "…")
+		if i == m.sourceCursor {
+			line = cursorStyle.Render(pad(line, width))
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (m *model) postLines(width, height int) []string {
+	if len(m.posts) == 0 {
+		return []string{dimStyle.Render("no posts")}
+	}
+
+	// keep the cursor on screen
+	if m.postCursor < m.postOffset {
+		m.postOffset = m.postCursor
+	}
+	if m.postCursor >= m.postOffset+height {
+		m.postOffset = m.postCursor - height + 1
+	}
+
+	var lines []string
+	for i := m.postOffset; i < len(m.posts) && len(lines) < height; i++ {
+		post := m.posts[i]
+		marker := "  "
+		switch {
+		case post.SavedAt.Valid:
+			marker = "★ "
+		case !post.ReadAt.Valid:
+			marker = "● "
+		}
+		line := ansi.Truncate(marker+post.Title, width, "…")
+		switch {
+		case i == m.postCursor:
+			line = cursorStyle.Render(pad(line, width))
+		case post.ReadAt.Valid:
+			line = dimStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (m *model) visibleReaderLines(height int) []string {
+	start := min(m.readerScroll, len(m.readerLines))
+	end := min(start+height, len(m.readerLines))
+	return m.readerLines[start:end]
+}
+
+// renderReader lays out the selected post for the reader pane, again when
+// the selection or the window size changes
+func (m *model) renderReader() {
+	post, ok := m.selected()
+	if !ok || m.width == 0 {
+		m.readerLines = nil
+		return
+	}
+	postsWidth := max(20, (m.width-sourcesWidth)*2/5)
+	width := max(18, m.width-sourcesWidth-postsWidth-2)
+
+	var b strings.Builder
+	b.WriteString(headingStyle.Render(wrapText(post.Title, width)) + "\n")
+	meta := []string{m.opts.ShortID(post.ID), post.FeedName}
+	if post.Author.Valid {
+		meta = append(meta, post.Author.String)
+	}
+	meta = append(meta, post.PublishedAt.Format("2006-01-02 15:04"))
+	b.WriteString(dimStyle.Render(wrapText(strings.Join(meta, " · "), width)) + "\n")
+	if len(post.Categories) > 0 {
+		b.WriteString(dimStyle.Render(wrapText("#"+strings.Join(post.Categories, " #"), width)) + "\n")
+	}
+	b.WriteString(dimStyle.Render(ansi.Truncate(post.Url, width, "…")) + "\n\n")
+
+	body := cmp.Or(post.FullContent.String, post.Content.String, post.Description.String)
+	if body == "" {
+		b.WriteString(dimStyle.Render("no content, press o to open the post in the browser"))
+	} else {
+		b.WriteString(htmltext.Render(body, htmltext.Options{Width: width}))
+	}
+
+	m.readerLines = strings.Split(b.String(), "\n")
+	for i, line := range m.readerLines {
+		m.readerLines[i] = ansi.Truncate(line, width, "")
+	}
+}
+
+// helpers
+func (m *model) selected() (database.GetUserPostsRow, bool) {
+	if m.postCursor < 0 || m.postCursor >= len(m.posts) {
+		return database.GetUserPostsRow{}, false
+	}
+	return m.posts[m.postCursor], true
+}
+
+func (m *model) selectedID() uuid.UUID {
+	post, _ := m.selected()
+	return post.ID
+}
+
+// listHeight is the number of content lines in a pane, without borders and
+// the status line
+func (m *model) listHeight() int {
+	return max(1, m.height-3)
+}
+
+func pad(s string, width int) string {
+	if w := ansi.StringWidth(s); w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	return s
+}
+
+func wrapText(s string, width int) string {
+	return htmltext.Render(html.EscapeString(s), htmltext.Options{Width: width, Links: htmltext.LinksNone})
+}
@@ -0,0 +1,135 @@
+// Package urlcanon puts feed and post urls in one canonical form, so the
+// same page isn't stored twice because one link has a trailing slash, an
+// upper case host or a utm_source parameter.
+package urlcanon
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// errors
+var ErrNotHTTP = errors.New("not an http(s) url")
+
+// trackingParams are removed from the query, prefixes end in an underscore
+var trackingParams = []string{
+	"utm_", "fbclid", "gclid", "dclid", "gbraid", "wbraid", "msclkid", "mc_cid", "mc_eid",
+	"_ga", "_gl", "igshid", "yclid", "twclid", "oly_anon_id", "oly_enc_id", "vero_id",
+}
+
+// functions
+
+// Canonicalize returns the canonical form of an http(s) url:
+//   - scheme and host in lower case, without the default port
+//   - no fragment and no tracking parameters (utm_*, fbclid, gclid, ...),
+//     the other parameters keep their order and encoding
+//   - no trailing slash, except for the root path
+//
+// The scheme itself is kept, some sites still only serve http. Variants
+// finds a url under either scheme.
+func Canonicalize(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ErrNotHTTP
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	switch port := u.Port(); {
+	case port == "", u.Scheme == "http" && port == "80", u.Scheme == "https" && port == "443":
+	default:
+		host += ":" + port
+	}
+	u.Host = host
+
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.RawQuery = stripTracking(u.RawQuery)
+	u.ForceQuery = false
+
+	path := strings.TrimRight(u.EscapedPath(), "/")
+	if path == "" {
+		path = "/"
+	}
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		u.Path = unescaped
+		u.RawPath = path
+	}
+	if u.EscapedPath() != path {
+		u.RawPath = ""
+	}
+	return u.String(), nil
+}
+
+// Variants lists the forms a url may be stored under: the canonical form
+// under both schemes and raw itself, for rows stored before
+// canonicalization or that it couldn't handle.
+func Variants(raw string) []string {
+	canonical, err := Canonicalize(raw)
+	if err != nil {
+		return []string{raw}
+	}
+	variants := []string{canonical}
+	if rest, ok := strings.CutPrefix(canonical, "https://"); ok {
+		variants = append(variants, "http://"+rest)
+	} else if rest, ok := strings.CutPrefix(canonical, "http://"); ok {
+		variants = append(variants, "https://"+rest)
+	}
+	if raw != canonical {
+		variants = append(variants, raw)
+	}
+	return variants
+}
+
+// OrRaw canonicalizes raw, or returns it unchanged when that fails, e.g.
+// for the odd post link that isn't http(s).
+func OrRaw(raw string) string {
+	if canonical, err := Canonicalize(raw); err == nil {
+		return canonical
+	}
+	return raw
+}
+
+// IsTrackingParam reports whether a query parameter only tracks where a
+// click came from.
+func IsTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	for _, param := range trackingParams {
+		if key == param || (strings.HasSuffix(param, "_") && strings.HasPrefix(key, param)) {
+			return true
+		}
+	}
+	return false
+}
+
+// helpers
+
+// stripTracking drops tracking parameters from a raw query without
+// reordering or re-encoding the rest
+func stripTracking(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	var kept []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if IsTrackingParam(key) {
+			continue
+		}
+		kept = append(kept, pair)
+	}
+	return strings.Join(kept, "&")
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/curator4/gator/internal/database"
+	"github.com/curator4/gator/internal/htmltext"
+	"golang.org/x/term"
+)
+
+// handlers
+func handlerOpen(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("open expects a post id, index or url")
+	}
+	post, err := resolvePost(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	if err := openURL(post.Url); err != nil {
+		return err
+	}
+	fmt.Printf("opened %s\n", post.Url)
+	return markRead(s, user, post)
+}
+
+func handlerRead(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("read expects a post id, index or url")
+	}
+	post, err := resolvePost(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	feed, err := s.db.GetFeedByID(context.Background(), post.FeedID)
+	if err != nil {
+		return err
+	}
+	opts, err := previewOptions(s)
+	if err != nil {
+		return err
+	}
+	opts.MaxLength = 0
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s\n", post.Title)
+	fmt.Fprintf(&b, "Feed: %s\n", feed.Name)
+	if post.Author.Valid {
+		fmt.Fprintf(&b, "Author: %s\n", post.Author.String)
+	}
+	fmt.Fprintf(&b, "Published: %s\n", post.PublishedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "URL: %s\n", post.Url)
+	if body := cmp.Or(post.FullContent.String, post.Content.String, post.Description.String); body != "" {
+		fmt.Fprintf(&b, "\n%s\n", htmltext.Render(body, opts))
+	} else {
+		fmt.Fprintf(&b, "\nno content stored, try: gator open %s\n", cmd.args[0])
+	}
+
+	if err := page(b.Bytes()); err != nil {
+		return err
+	}
+	return markRead(s, user, post)
+}
+
+// helpers
+
+func markRead(s *state, user database.User, post database.Post) error {
+	return s.db.SetPostRead(context.Background(), database.SetPostReadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+		ReadAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+}
+
+// page shows text through $PAGER (less by default) when stdout is a
+// terminal, and writes it out as is otherwise
+func page(text []byte) error {
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		_, err := os.Stdout.Write(text)
+		return err
+	}
+	pager := strings.Fields(os.Getenv("PAGER"))
+	if len(pager) == 0 {
+		pager = []string{"less", "-R"}
+	}
+	if _, err := exec.LookPath(pager[0]); err != nil {
+		_, err := os.Stdout.Write(text)
+		return err
+	}
+	cmd := exec.Command(pager[0], pager[1:]...)
+	cmd.Stdin = bytes.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running pager %s: %w", pager[0], err)
+	}
+	return nil
+}
+
+// openURL shows a post in the browser: $BROWSER if set, otherwise the
+// system opener. Urls come from feeds, so only http(s) is let through.
+func openURL(target string) error {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("refusing to open %q, not an http(s) url", target)
+	}
+
+	var cmd *exec.Cmd
+	if browser := os.Getenv("BROWSER"); browser != "" {
+		// $BROWSER may list several commands separated by colons, and may
+		// say where the url goes with %s
+		first := strings.Split(browser, ":")[0]
+		args := strings.Fields(first)
+		placed := false
+		for i, arg := range args {
+			if strings.Contains(arg, "%s") {
+				args[i] = strings.ReplaceAll(arg, "%s", target)
+				placed = true
+			}
+		}
+		if !placed {
+			args = append(args, target)
+		}
+		cmd = exec.Command(args[0], args[1:]...)
+	} else {
+		switch runtime.GOOS {
+		case "darwin":
+			cmd = exec.Command("open", target)
+		case "windows":
+			cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+		default:
+			cmd = exec.Command("xdg-open", target)
+		}
+	}
+
+	// the opener must not write over a full screen ui
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("opening browser: %w", err)
+	}
+	go cmd.Wait()
+	return nil
+}
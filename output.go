@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// output formats for list commands, picked with the global --output flag
+const (
+	outputText     = "text"
+	outputTable    = "table"
+	outputJSON     = "json"
+	outputNDJSON   = "ndjson"
+	outputCSV      = "csv"
+	outputTemplate = "template"
+)
+
+// structs
+type outputFormat struct {
+	kind string
+	tmpl *template.Template
+}
+
+// listing describes how a list command prints its rows. items are structs
+// with json tags, json and templates see them as they are, table and csv go
+// through columns and row, text is the command's own human readable output.
+type listing[T any] struct {
+	columns []string
+	row     func(T) []string
+	text    func([]T)
+}
+
+// rows of the list commands, the json field names are part of the output
+type userRow struct {
+	Name      string    `json:"name"`
+	Current   bool      `json:"current"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type feedRow struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	AddedBy  string `json:"added_by"`
+	AuthType string `json:"auth_type,omitempty"`
+}
+
+type followRow struct {
+	Feed       string    `json:"feed"`
+	URL        string    `json:"url"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type postRow struct {
+	ID          uuid.UUID      `json:"id"`
+	ShortID     string         `json:"short_id"`
+	Title       string         `json:"title"`
+	URL         string         `json:"url"`
+	Feed        string         `json:"feed"`
+	Author      string         `json:"author,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Content     string         `json:"content,omitempty"`
+	FullContent string         `json:"full_content,omitempty"`
+	CommentsURL string         `json:"comments_url,omitempty"`
+	Categories  []string       `json:"categories,omitempty"`
+	Enclosures  []enclosureRow `json:"enclosures,omitempty"`
+	AlsoIn      []sourceRow    `json:"also_in,omitempty"`
+	PublishedAt time.Time      `json:"published_at"`
+}
+
+// sourceRow is another feed's post of the same story
+type sourceRow struct {
+	ID      uuid.UUID `json:"id"`
+	ShortID string    `json:"short_id"`
+	Feed    string    `json:"feed"`
+	URL     string    `json:"url"`
+}
+
+type enclosureRow struct {
+	URL    string `json:"url"`
+	Length int64  `json:"length,omitempty"`
+	Type   string `json:"type,omitempty"`
+}
+
+// functions
+
+// extractOutputFlag reads --output/-o in front of the command name, the
+// arguments after it are left to the command. It accepts text, table, json,
+// ndjson, csv or template=<go template>.
+func extractOutputFlag(args []string) (outputFormat, []string, error) {
+	format := outputFormat{kind: outputText}
+
+	for len(args) > 0 {
+		arg := args[0]
+		var value string
+		switch {
+		case arg == "--":
+			return format, args[1:], nil
+		case arg == "--output" || arg == "-o":
+			if len(args) < 2 {
+				return outputFormat{}, nil, fmt.Errorf("%s expects a format", arg)
+			}
+			value = args[1]
+			args = args[2:]
+		case strings.HasPrefix(arg, "--output="):
+			value = strings.TrimPrefix(arg, "--output=")
+			args = args[1:]
+		default:
+			return format, args, nil
+		}
+
+		parsed, err := parseOutputFormat(value)
+		if err != nil {
+			return outputFormat{}, nil, err
+		}
+		format = parsed
+	}
+	return format, args, nil
+}
+
+func parseOutputFormat(value string) (outputFormat, error) {
+	if text, ok := strings.CutPrefix(value, "template="); ok {
+		// one item per line unless the template says otherwise
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		tmpl, err := template.New("output").Parse(text)
+		if err != nil {
+			return outputFormat{}, fmt.Errorf("invalid output template: %w", err)
+		}
+		return outputFormat{kind: outputTemplate, tmpl: tmpl}, nil
+	}
+
+	switch value {
+	case outputText, outputTable, outputJSON, outputNDJSON, outputCSV:
+		return outputFormat{kind: value}, nil
+	case outputTemplate:
+		return outputFormat{}, errors.New("template output needs a format string, e.g. --output 'template={{.Name}}'")
+	}
+	return outputFormat{}, fmt.Errorf("unknown output format %q, use text, table, json, ndjson, csv or template=...", value)
+}
+
+// printList writes items in the format chosen with --output
+func printList[T any](s *state, items []T, l listing[T]) error {
+	out := os.Stdout
+
+	switch s.output.kind {
+	case outputTable:
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		header := make([]string, len(l.columns))
+		for i, column := range l.columns {
+			header[i] = strings.ToUpper(column)
+		}
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+		for _, item := range items {
+			fmt.Fprintln(w, strings.Join(sanitizeCells(l.row(item)), "\t"))
+		}
+		return w.Flush()
+
+	case outputCSV:
+		w := csv.NewWriter(out)
+		if err := w.Write(l.columns); err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := w.Write(l.row(item)); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+
+	case outputJSON:
+		if items == nil {
+			items = []T{}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(items)
+
+	case outputNDJSON:
+		encoder := json.NewEncoder(out)
+		for _, item := range items {
+			if err := encoder.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case outputTemplate:
+		for _, item := range items {
+			if err := s.output.tmpl.Execute(out, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	l.text(items)
+	return nil
+}
+
+// helpers
+
+// sanitizeCells keeps multi-line values from breaking table rows
+func sanitizeCells(cells []string) []string {
+	replacer := strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+	out := make([]string, len(cells))
+	for i, cell := range cells {
+		out[i] = replacer.Replace(cell)
+	}
+	return out
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/curator4/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// structs
+type episodeRow struct {
+	ID          uuid.UUID `json:"id"`
+	ShortID     string    `json:"short_id"`
+	Title       string    `json:"title"`
+	Feed        string    `json:"feed"`
+	PublishedAt time.Time `json:"published_at"`
+	Duration    int32     `json:"duration_seconds,omitempty"`
+	AudioURL    string    `json:"audio_url"`
+	Length      int64     `json:"length,omitempty"`
+	Type        string    `json:"type,omitempty"`
+	ImageURL    string    `json:"image_url,omitempty"`
+}
+
+// handlers
+func handlerEpisodes(s *state, cmd command, user database.User) error {
+	limit := 20
+	params := database.GetUserEpisodesParams{UserID: user.ID}
+
+	positional := 0
+	for i := 0; i < len(cmd.args); i++ {
+		switch arg := cmd.args[i]; arg {
+		case "--feed":
+			if i+1 >= len(cmd.args) {
+				return errors.New("--feed expects a feed url")
+			}
+			i++
+			feed, err := findFeed(s, cmd.args[i])
+			if err != nil {
+				return err
+			}
+			params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+		default:
+			if positional > 0 {
+				return errors.New("too many args")
+			}
+			positional++
+			var err error
+			limit, err = strconv.Atoi(arg)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	params.Limit = int32(limit)
+
+	episodes, err := s.db.GetUserEpisodes(context.Background(), params)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]episodeRow, 0, len(episodes))
+	for _, episode := range episodes {
+		rows = append(rows, episodeRow{
+			ID:          episode.ID,
+			ShortID:     shortID(episode.ID),
+			Title:       episode.Title,
+			Feed:        episode.FeedName,
+			PublishedAt: episode.PublishedAt,
+			Duration:    episode.DurationSeconds.Int32,
+			AudioURL:    episode.EnclosureUrl,
+			Length:      episode.Length.Int64,
+			Type:        episode.MimeType.String,
+			ImageURL:    episode.ImageUrl.String,
+		})
+	}
+
+	return printList(s, rows, listing[episodeRow]{
+		columns: []string{"id", "published_at", "feed", "title", "duration", "size", "audio_url"},
+		row: func(r episodeRow) []string {
+			return []string{
+				r.ShortID,
+				r.PublishedAt.Format(time.RFC3339),
+				r.Feed,
+				r.Title,
+				formatEpisodeDuration(r.Duration),
+				formatBytes(r.Length),
+				r.AudioURL,
+			}
+		},
+		text: func(rows []episodeRow) {
+			fmt.Printf("Found %d episodes:\n", len(rows))
+			for _, r := range rows {
+				fmt.Printf("%s  %s  %s\n", r.PublishedAt.Format("2006-01-02"), r.Feed, r.Title)
+				details := []string{r.ShortID}
+				if r.Duration > 0 {
+					details = append(details, formatEpisodeDuration(r.Duration))
+				}
+				if r.Length > 0 {
+					details = append(details, formatBytes(r.Length))
+				}
+				fmt.Printf("            %s\n", strings.Join(details, " · "))
+			}
+		},
+	})
+}
+
+func handlerDownload(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("download expects a post id, index or url")
+	}
+
+	post, err := resolvePost(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	enclosures, err := s.db.GetPostEnclosures(context.Background(), []uuid.UUID{post.ID})
+	if err != nil {
+		return err
+	}
+	if len(enclosures) == 0 {
+		return fmt.Errorf("%q has no attachment to download", post.Title)
+	}
+	enclosure := enclosures[0]
+
+	feed, err := s.db.GetFeedByID(context.Background(), post.FeedID)
+	if err != nil {
+		return err
+	}
+	dir, err := downloadDir(s)
+	if err != nil {
+		return err
+	}
+	dir = filepath.Join(dir, safeFileName(feed.Name))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	target := filepath.Join(dir, episodeFileName(post, enclosure))
+	if _, err := os.Stat(target); err == nil {
+		fmt.Printf("already downloaded: %s\n", target)
+		return nil
+	}
+
+	// ctrl-c leaves the .part file behind to resume from
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("downloading %s\n", post.Title)
+	last := time.Time{}
+	err = s.fetcher.Download(ctx, enclosure.Url, target, func(written, total int64) {
+		if time.Since(last) < 200*time.Millisecond && written != total {
+			return
+		}
+		last = time.Now()
+		if total > 0 {
+			fmt.Printf("\r  %s / %s (%d%%)   ", formatBytes(written), formatBytes(total), written*100/total)
+		} else {
+			fmt.Printf("\r  %s   ", formatBytes(written))
+		}
+	})
+	fmt.Println()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("saved to %s\n", target)
+	return nil
+}
+
+// helpers
+
+func downloadDir(s *state) (string, error) {
+	if s.cfg.DownloadDir != "" {
+		dir := s.cfg.DownloadDir
+		if rest, ok := strings.CutPrefix(dir, "~/"); ok {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			dir = filepath.Join(home, rest)
+		}
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Downloads", "gator"), nil
+}
+
+// episodeFileName names the file after the episode, with the extension of
+// the enclosure url or, failing that, of its type
+func episodeFileName(post database.Post, enclosure database.PostEnclosure) string {
+	ext := ""
+	if u, err := url.Parse(enclosure.Url); err == nil {
+		ext = path.Ext(u.Path)
+	}
+	if (ext == "" || len(ext) > 6) && enclosure.MimeType.Valid {
+		if exts, err := mime.ExtensionsByType(enclosure.MimeType.String); err == nil && len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	name := post.PublishedAt.Format("2006-01-02") + " " + safeFileName(post.Title)
+	return name + ext
+}
+
+// safeFileName keeps names portable and free of path separators
+func safeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '-'
+		}
+		if r < ' ' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), ".")
+	if runes := []rune(name); len(runes) > 120 {
+		name = string(runes[:120])
+	}
+	if name == "" {
+		name = "untitled"
+	}
+	return name
+}
+
+func formatEpisodeDuration(seconds int32) string {
+	if seconds <= 0 {
+		return ""
+	}
+	h, m, sec := seconds/3600, seconds%3600/60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, sec)
+	}
+	return fmt.Sprintf("%d:%02d", m, sec)
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n <= 0:
+		return ""
+	case n < 1<<10:
+		return fmt.Sprintf("%d B", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	case n < 1<<30:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	}
+	return fmt.Sprintf("%.2f GB", float64(n)/(1<<30))
+}
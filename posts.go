@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/curator4/gator/internal/database"
+	"github.com/curator4/gator/internal/urlcanon"
+	"github.com/google/uuid"
+)
+
+// consts
+
+// short ids are the first characters of a post's uuid. Output shows
+// shortIDLength of them, at least minShortIDLength are needed to look a
+// post up, so shorter numbers stay free for browse indexes.
+const (
+	shortIDLength    = 8
+	minShortIDLength = 6
+)
+
+// helpers
+
+// shortID is the stable identifier output shows for a post
+func shortID(id uuid.UUID) string {
+	return id.String()[:shortIDLength]
+}
+
+// findByIDPrefix finds the one item whose id starts with ref. kind names
+// the items in errors and list is the command that lists them.
+func findByIDPrefix[T any](items []T, id func(T) uuid.UUID, ref, kind, list string) (T, error) {
+	var found []T
+	for _, item := range items {
+		if strings.HasPrefix(id(item).String(), strings.ToLower(ref)) {
+			found = append(found, item)
+		}
+	}
+	var zero T
+	switch len(found) {
+	case 0:
+		return zero, fmt.Errorf("no %s with id %s, see gator %s", kind, ref, list)
+	case 1:
+		return found[0], nil
+	}
+	return zero, fmt.Errorf("%s id %s is ambiguous, use more characters of it", kind, ref)
+}
+
+// resolvePost finds the post a command refers to, among the feeds the user
+// follows. ref is, in order:
+//   - a number shorter than a short id, the position in your timeline as
+//     browse lists it, 1 being the newest post
+//   - a full uuid
+//   - a short id, any unambiguous prefix of the uuid
+//   - the post's url
+func resolvePost(s *state, user database.User, ref string) (database.Post, error) {
+	ctx := context.Background()
+
+	if index, err := strconv.Atoi(ref); err == nil && len(ref) < minShortIDLength {
+		if index < 1 {
+			return database.Post{}, fmt.Errorf("invalid post index %d, browse numbers posts from 1", index)
+		}
+		// counted like browse counts, with duplicate stories collapsed
+		entries, err := loadTimeline(s, database.GetUserPostsParams{UserID: user.ID}, index)
+		if err != nil {
+			return database.Post{}, err
+		}
+		if len(entries) < index {
+			return database.Post{}, fmt.Errorf("no post at index %d", index)
+		}
+		return s.db.GetPostByID(ctx, entries[index-1].post.ID)
+	}
+
+	if id, err := uuid.Parse(ref); err == nil {
+		post, err := s.db.GetUserPostByID(ctx, database.GetUserPostByIDParams{UserID: user.ID, ID: id})
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.Post{}, fmt.Errorf("no post with id %s", ref)
+		}
+		return post, err
+	}
+
+	if isIDPrefix(ref) {
+		posts, err := s.db.GetPostsByIDPrefix(ctx, database.GetPostsByIDPrefixParams{
+			UserID: user.ID,
+			Prefix: strings.ToLower(ref),
+		})
+		if err != nil {
+			return database.Post{}, err
+		}
+		switch len(posts) {
+		case 0:
+			return database.Post{}, fmt.Errorf("no post with id %s", ref)
+		case 1:
+			return posts[0], nil
+		default:
+			return database.Post{}, fmt.Errorf("post id %s is ambiguous, use more characters of it", ref)
+		}
+	}
+
+	post, err := s.db.GetUserPostByURLs(ctx, database.GetUserPostByURLsParams{
+		UserID: user.ID,
+		Urls:   urlcanon.Variants(ref),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Post{}, fmt.Errorf("no post with id or url %s", ref)
+	}
+	return post, err
+}
+
+// isIDPrefix reports whether ref can be the start of a uuid
+func isIDPrefix(ref string) bool {
+	if len(ref) < minShortIDLength || len(ref) > 36 {
+		return false
+	}
+	for _, r := range strings.ToLower(ref) {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/curator4/gator/internal/database"
+	"github.com/curator4/gator/internal/rules"
+	"github.com/google/uuid"
+)
+
+// consts
+const rulesUsage = "usage: rule add [--field any|title|description|author|category] [--regex] <pattern> hide|read|tag <name>\n" +
+	"       rule list\n" +
+	"       rule remove <id>\n" +
+	"       rule apply [id]"
+
+// structs
+type ruleRow struct {
+	ID        uuid.UUID `json:"id"`
+	ShortID   string    `json:"short_id"`
+	Field     string    `json:"field"`
+	MatchType string    `json:"match_type"`
+	Pattern   string    `json:"pattern"`
+	Action    string    `json:"action"`
+	Tag       string    `json:"tag,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ruleCounts sums up what rules did to posts
+type ruleCounts struct {
+	hidden, read, tagged int
+}
+
+// handlers
+func handlerRule(s *state, cmd command, user database.User) error {
+	if len(cmd.args) == 0 {
+		return errors.New(rulesUsage)
+	}
+	args := cmd.args[1:]
+	switch cmd.args[0] {
+	case "add":
+		return ruleAdd(s, args, user)
+	case "list":
+		if len(args) != 0 {
+			return errors.New("rule list takes no arguments")
+		}
+		return ruleList(s, user)
+	case "remove":
+		if len(args) != 1 {
+			return errors.New("rule remove expects a rule id")
+		}
+		return ruleRemove(s, args[0], user)
+	case "apply":
+		if len(args) > 1 {
+			return errors.New("rule apply expects at most a rule id")
+		}
+		return ruleApply(s, args, user)
+	}
+	return errors.New(rulesUsage)
+}
+
+func ruleAdd(s *state, args []string, user database.User) error {
+	params := database.CreateRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		Field:     rules.FieldAny,
+		MatchType: rules.MatchKeyword,
+	}
+
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "--field":
+			if i+1 >= len(args) {
+				return errors.New("--field expects " + strings.Join(rules.Fields, ", "))
+			}
+			i++
+			params.Field = args[i]
+		case "--regex":
+			params.MatchType = rules.MatchRegex
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) < 2 {
+		return errors.New(rulesUsage)
+	}
+	params.Pattern = positional[0]
+	params.Action = positional[1]
+	switch {
+	case params.Action == rules.ActionTag && len(positional) == 3:
+		params.Tag = sql.NullString{String: strings.TrimSpace(positional[2]), Valid: true}
+	case params.Action == rules.ActionTag:
+		return errors.New("rule add ... tag expects a tag name")
+	case len(positional) > 2:
+		return errors.New("too many args")
+	}
+
+	compiled, err := rules.Compile(database.Rule(params))
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db.CreateRule(context.Background(), params); err != nil {
+		return err
+	}
+	fmt.Printf("added rule %s: %s\n", shortID(params.ID), compiled)
+	fmt.Printf("it applies to new posts, run `gator rule apply %s` for the ones you have\n", shortID(params.ID))
+	return nil
+}
+
+func ruleList(s *state, user database.User) error {
+	stored, err := s.db.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]ruleRow, 0, len(stored))
+	for _, rule := range stored {
+		rows = append(rows, ruleRow{
+			ID:        rule.ID,
+			ShortID:   shortID(rule.ID),
+			Field:     rule.Field,
+			MatchType: rule.MatchType,
+			Pattern:   rule.Pattern,
+			Action:    rule.Action,
+			Tag:       rule.Tag.String,
+			CreatedAt: rule.CreatedAt,
+		})
+	}
+
+	return printList(s, rows, listing[ruleRow]{
+		columns: []string{"id", "field", "match_type", "pattern", "action", "tag", "created_at"},
+		row: func(r ruleRow) []string {
+			return []string{r.ShortID, r.Field, r.MatchType, r.Pattern, r.Action, r.Tag, r.CreatedAt.Format(time.RFC3339)}
+		},
+		text: func(rows []ruleRow) {
+			if len(rows) == 0 {
+				fmt.Println("no rules, add one with: gator rule add <pattern> hide|read|tag <name>")
+				return
+			}
+			for i, r := range rows {
+				fmt.Printf("%s  %s\n", r.ShortID, &rules.Rule{Rule: stored[i]})
+			}
+		},
+	})
+}
+
+func ruleRemove(s *state, ref string, user database.User) error {
+	rule, err := findRule(s, ref, user)
+	if err != nil {
+		return err
+	}
+	params := database.DeleteRuleParams{ID: rule.ID, UserID: user.ID}
+	if err := s.db.DeleteRule(context.Background(), params); err != nil {
+		return err
+	}
+	fmt.Printf("removed rule %s: %s\n", shortID(rule.ID), &rules.Rule{Rule: rule})
+	return nil
+}
+
+// ruleApply runs rules over the posts you already have. Hidden posts stay
+// hidden, removing a rule doesn't undo what it did.
+func ruleApply(s *state, args []string, user database.User) error {
+	var stored []database.Rule
+	if len(args) == 1 {
+		rule, err := findRule(s, args[0], user)
+		if err != nil {
+			return err
+		}
+		stored = []database.Rule{rule}
+	} else {
+		var err error
+		stored, err = s.db.GetRulesForUser(context.Background(), user.ID)
+		if err != nil {
+			return err
+		}
+	}
+	compiled := rules.CompileAll(stored)
+	if len(compiled) == 0 {
+		fmt.Println("no rules to apply")
+		return nil
+	}
+
+	// collect first, hiding posts moves the pages of the query
+	const page = 500
+	var posts []database.GetUserPostsRow
+	for offset := 0; ; offset += page {
+		batch, err := s.db.GetUserPosts(context.Background(), database.GetUserPostsParams{
+			UserID: user.ID,
+			Limit:  page,
+			Offset: int32(offset),
+		})
+		if err != nil {
+			return err
+		}
+		posts = append(posts, batch...)
+		if len(batch) < page {
+			break
+		}
+	}
+
+	total := ruleCounts{}
+	for _, post := range posts {
+		counts, err := applyRules(s, compiled, user.ID, post.ID, rules.Post{
+			Title:       post.Title,
+			Description: cmp.Or(post.Description.String, post.Content.String),
+			Author:      post.Author.String,
+			Categories:  post.Categories,
+		}, post.ReadAt.Valid)
+		if err != nil {
+			return err
+		}
+		total.hidden += counts.hidden
+		total.read += counts.read
+		total.tagged += counts.tagged
+	}
+	fmt.Printf("checked %d posts: %d hidden, %d marked read, %d tagged\n", len(posts), total.hidden, total.read, total.tagged)
+	return nil
+}
+
+// helpers
+
+// findRule looks up one of the user's rules by id or id prefix
+func findRule(s *state, ref string, user database.User) (database.Rule, error) {
+	stored, err := s.db.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return database.Rule{}, err
+	}
+	return findByIDPrefix(stored, func(rule database.Rule) uuid.UUID { return rule.ID }, ref, "rule", "rule list")
+}
+
+// feedRules loads the rules of everyone following a feed, by user
+func feedRules(s *state, feedID uuid.UUID) (map[uuid.UUID][]*rules.Rule, error) {
+	stored, err := s.db.GetRulesForFeed(context.Background(), feedID)
+	if err != nil {
+		return nil, err
+	}
+	by_user := make(map[uuid.UUID][]*rules.Rule)
+	for _, rule := range rules.CompileAll(stored) {
+		by_user[rule.UserID] = append(by_user[rule.UserID], rule)
+	}
+	return by_user, nil
+}
+
+// applyRules does what the matching rules of a user say to a post. read
+// tells whether the post is read already, so its read time is kept.
+func applyRules(s *state, user_rules []*rules.Rule, user_id, post_id uuid.UUID, post rules.Post, read bool) (ruleCounts, error) {
+	counts := ruleCounts{}
+	now := sql.NullTime{Time: time.Now(), Valid: true}
+	post.Categories = slices.Clone(post.Categories)
+	hidden := false
+	for _, rule := range user_rules {
+		if !rule.Match(post) {
+			continue
+		}
+		switch rule.Action {
+		case rules.ActionHide:
+			if hidden {
+				continue
+			}
+			params := database.SetPostHiddenParams{UserID: user_id, PostID: post_id, HiddenAt: now}
+			if err := s.db.SetPostHidden(context.Background(), params); err != nil {
+				return counts, err
+			}
+			hidden = true
+			counts.hidden++
+		case rules.ActionRead:
+			if read {
+				continue
+			}
+			params := database.SetPostReadParams{UserID: user_id, PostID: post_id, ReadAt: now}
+			if err := s.db.SetPostRead(context.Background(), params); err != nil {
+				return counts, err
+			}
+			read = true
+			counts.read++
+		case rules.ActionTag:
+			if containsFold(post.Categories, rule.Tag.String) {
+				continue
+			}
+			params := database.CreatePostTagParams{UserID: user_id, PostID: post_id, Name: rule.Tag.String}
+			if err := s.db.CreatePostTag(context.Background(), params); err != nil {
+				return counts, err
+			}
+			post.Categories = append(post.Categories, rule.Tag.String)
+			counts.tagged++
+		}
+	}
+	return counts, nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+-- name: CreateDigestSchedule :one
+INSERT INTO digest_schedules (id, created_at, user_id, send_interval, format, group_by, channel, target)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7,
+  $8
+)
+RETURNING *;
+-- name: GetDigestSchedulesForUser :many
+SELECT * FROM digest_schedules
+WHERE user_id = $1
+ORDER BY created_at;
+-- name: GetDueDigestSchedules :many
+SELECT * FROM digest_schedules
+WHERE COALESCE(last_sent_at, created_at) + send_interval * interval '1 second' <= sqlc.arg(now)::timestamp
+ORDER BY created_at;
+-- name: SetDigestScheduleSent :exec
+UPDATE digest_schedules SET last_sent_at = $2
+WHERE id = $1;
+-- name: DeleteDigestSchedule :exec
+DELETE FROM digest_schedules
+WHERE id = $1 AND user_id = $2;
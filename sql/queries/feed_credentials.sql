@@ -0,0 +1,23 @@
+-- name: SetFeedCredential :exec
+INSERT INTO feed_credentials (feed_id, created_at, updated_at, auth_type, username, query_param, secret)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    auth_type = EXCLUDED.auth_type,
+    username = EXCLUDED.username,
+    query_param = EXCLUDED.query_param,
+    secret = EXCLUDED.secret;
+-- name: GetFeedCredential :one
+SELECT * FROM feed_credentials
+WHERE feed_id = $1;
+-- name: DeleteFeedCredential :exec
+DELETE FROM feed_credentials
+WHERE feed_id = $1;
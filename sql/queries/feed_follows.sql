@@ -18,7 +18,7 @@ FROM inserted_feed_follow
 INNER JOIN users ON inserted_feed_follow.user_id = users.id
 INNER JOIN feeds ON inserted_feed_follow.feed_id = feeds.id;
 -- name: GetFeedFollowsForUser :many
-SELECT users.name as user_name, feeds.name as feed_name
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.feed_id, users.name as user_name, feeds.name as feed_name, feeds.url as feed_url
 FROM feed_follows
 INNER JOIN users ON feed_follows.user_id = users.id
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
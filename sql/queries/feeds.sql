@@ -10,17 +10,42 @@ VALUES (
 )
 RETURNING *;
 -- name: GetFeeds :many
-SELECT feeds.name, feeds.url, users.name as username
+SELECT feeds.id, feeds.created_at, feeds.name, feeds.url, feeds.user_id, users.name as username, feed_credentials.auth_type
 FROM feeds
-JOIN users ON feeds.user_id = users.id;
+JOIN users ON feeds.user_id = users.id
+LEFT JOIN feed_credentials ON feed_credentials.feed_id = feeds.id;
 -- name: GetFeedByURL :one
 SELECT * FROM feeds
 WHERE feeds.url = $1;
+-- name: GetFeedByURLs :one
+SELECT * FROM feeds
+WHERE feeds.url = ANY(sqlc.arg(urls)::text[])
+ORDER BY created_at
+LIMIT 1;
 -- name: MarkFeedFetched :exec
 UPDATE feeds
 SET last_fetched_at = $1, updated_at = $2
 WHERE id = $3;
--- name: GetNextFeedToFetch :one
+-- name: GetNextFeedsToFetch :many
 SELECT * FROM feeds
+WHERE next_fetch_at IS NULL OR next_fetch_at <= sqlc.arg(now)::timestamp
 ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
+LIMIT sqlc.arg(max_feeds);
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $1, updated_at = $2
+WHERE id = $3;
+-- name: DeferFeedFetch :exec
+UPDATE feeds
+SET next_fetch_at = $1, updated_at = $2
+WHERE id = $3;
+-- name: GetFeedByID :one
+SELECT * FROM feeds
+WHERE id = $1;
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+-- name: SetFeedFullContent :exec
+UPDATE feeds
+SET fetch_full_content = $1, updated_at = $2
+WHERE id = $3;
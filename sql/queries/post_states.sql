@@ -0,0 +1,15 @@
+-- name: SetPostRead :exec
+INSERT INTO post_states (user_id, post_id, read_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = EXCLUDED.read_at;
+-- name: SetPostSaved :exec
+INSERT INTO post_states (user_id, post_id, saved_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET saved_at = EXCLUDED.saved_at;
+-- name: SetPostHidden :exec
+INSERT INTO post_states (user_id, post_id, hidden_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET hidden_at = EXCLUDED.hidden_at;
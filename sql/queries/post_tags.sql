@@ -0,0 +1,4 @@
+-- name: CreatePostTag :exec
+INSERT INTO post_tags (user_id, post_id, name)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
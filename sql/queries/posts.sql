@@ -1,5 +1,5 @@
 -- name: CreatePost :exec
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, content, comments_url, duration_seconds, image_url, cluster_id)
 VALUES (
   $1,
   $2,
@@ -8,12 +8,136 @@ VALUES (
   $5,
   $6,
   $7,
-  $8
+  $8,
+  $9,
+  $10,
+  $11,
+  $12,
+  $13,
+  $14
 );
+-- name: CreatePostCategory :exec
+INSERT INTO post_categories (post_id, name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+-- name: CreatePostEnclosure :exec
+INSERT INTO post_enclosures (post_id, url, length, mime_type)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING;
+-- name: GetClusterCandidates :many
+SELECT id, cluster_id, url, title FROM posts
+WHERE feed_id <> $1 AND published_at >= $2
+ORDER BY published_at;
+-- name: GetPostEnclosures :many
+SELECT * FROM post_enclosures
+WHERE post_id = ANY(sqlc.arg(post_ids)::uuid[])
+ORDER BY post_id, url;
+-- name: GetPostByID :one
+SELECT * FROM posts
+WHERE id = $1;
+-- name: GetPostByURL :one
+SELECT * FROM posts
+WHERE url = $1;
+-- name: GetPostByURLs :one
+SELECT * FROM posts
+WHERE url = ANY(sqlc.arg(urls)::text[])
+ORDER BY created_at
+LIMIT 1;
+-- name: GetUserPostByID :one
+SELECT posts.* FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND posts.id = sqlc.arg(id);
+-- name: GetUserPostByURLs :one
+SELECT posts.* FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND posts.url = ANY(sqlc.arg(urls)::text[])
+ORDER BY posts.created_at
+LIMIT 1;
+-- name: GetPostsByIDPrefix :many
+SELECT posts.* FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND posts.id::text LIKE sqlc.arg(prefix)::text || '%'
+ORDER BY posts.published_at DESC
+LIMIT 2;
+-- name: GetUserCategories :many
+SELECT names.name, count(*) AS posts
+FROM (
+  SELECT post_categories.post_id, post_categories.name
+  FROM post_categories
+  INNER JOIN posts ON post_categories.post_id = posts.id
+  INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+  WHERE feed_follows.user_id = $1
+  UNION
+  SELECT post_tags.post_id, post_tags.name
+  FROM post_tags
+  WHERE post_tags.user_id = $1
+) AS names
+GROUP BY names.name
+ORDER BY count(*) DESC, names.name
+LIMIT $2;
+-- name: GetUserEpisodes :many
+SELECT posts.id, posts.title, posts.published_at, posts.duration_seconds, posts.image_url,
+  feeds.name AS feed_name,
+  post_enclosures.url AS enclosure_url, post_enclosures.length, post_enclosures.mime_type
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN post_enclosures ON post_enclosures.post_id = posts.id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND post_states.hidden_at IS NULL
+  AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+ORDER BY posts.published_at DESC, post_enclosures.url
+LIMIT sqlc.arg('limit');
 -- name: GetUserPosts :many
-SELECT posts.*, feeds.name AS feed_name FROM posts
+SELECT posts.*, feeds.name AS feed_name, feeds.url AS feed_url,
+  COALESCE(
+    (SELECT array_agg(names.name ORDER BY names.name) FROM (
+      SELECT post_categories.name FROM post_categories WHERE post_categories.post_id = posts.id
+      UNION
+      SELECT post_tags.name FROM post_tags WHERE post_tags.post_id = posts.id AND post_tags.user_id = feed_follows.user_id
+    ) AS names),
+    '{}'
+  )::text[] AS categories,
+  post_states.read_at, post_states.saved_at
+FROM posts
 INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
 INNER JOIN feeds ON posts.feed_id = feeds.id
-WHERE feed_follows.user_id = $1
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND post_states.hidden_at IS NULL
+  AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+  AND (sqlc.narg(since)::timestamp IS NULL OR posts.published_at >= sqlc.narg(since))
+  AND (sqlc.narg(added_since)::timestamp IS NULL OR posts.created_at >= sqlc.narg(added_since))
+  AND (sqlc.narg(cluster_ids)::uuid[] IS NULL OR posts.cluster_id = ANY(sqlc.narg(cluster_ids)::uuid[]))
+  AND (sqlc.narg(search)::text IS NULL
+    OR posts.title ILIKE '%' || sqlc.narg(search) || '%'
+    OR posts.description ILIKE '%' || sqlc.narg(search) || '%'
+    OR posts.content ILIKE '%' || sqlc.narg(search) || '%'
+    OR posts.full_content ILIKE '%' || sqlc.narg(search) || '%')
+  AND (sqlc.narg(category)::text IS NULL OR EXISTS (
+    SELECT 1 FROM post_categories
+    WHERE post_categories.post_id = posts.id
+      AND lower(post_categories.name) = lower(sqlc.narg(category))
+  ) OR EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.post_id = posts.id
+      AND post_tags.user_id = feed_follows.user_id
+      AND lower(post_tags.name) = lower(sqlc.narg(category))
+  ))
+  AND (NOT sqlc.arg(unread_only)::boolean OR post_states.read_at IS NULL)
+  AND (NOT sqlc.arg(saved_only)::boolean OR post_states.saved_at IS NOT NULL)
 ORDER BY posts.published_at DESC
-LIMIT $2;
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+-- name: GetUserLatestPostTime :one
+SELECT COALESCE(MAX(posts.created_at), 'epoch')::timestamp AS latest
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1;
+-- name: SetPostFullContent :exec
+UPDATE posts
+SET full_content = $1, updated_at = $2
+WHERE id = $3;
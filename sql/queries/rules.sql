@@ -0,0 +1,25 @@
+-- name: CreateRule :one
+INSERT INTO rules (id, created_at, user_id, field, match_type, pattern, action, tag)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7,
+  $8
+)
+RETURNING *;
+-- name: GetRulesForUser :many
+SELECT * FROM rules
+WHERE user_id = $1
+ORDER BY created_at;
+-- name: GetRulesForFeed :many
+SELECT rules.* FROM rules
+INNER JOIN feed_follows ON rules.user_id = feed_follows.user_id
+WHERE feed_follows.feed_id = $1
+ORDER BY rules.user_id, rules.created_at;
+-- name: DeleteRule :exec
+DELETE FROM rules
+WHERE id = $1 AND user_id = $2;
@@ -0,0 +1,15 @@
+-- name: CreateSession :exec
+INSERT INTO sessions (token_hash, created_at, expires_at, user_id)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4
+);
+-- name: GetUserBySession :one
+SELECT users.* FROM sessions
+INNER JOIN users ON sessions.user_id = users.id
+WHERE sessions.token_hash = $1 AND sessions.expires_at > $2;
+-- name: DeleteSession :exec
+DELETE FROM sessions
+WHERE token_hash = $1;
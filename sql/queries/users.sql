@@ -1,10 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, api_key_hash, password_hash)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5,
+    $6
 )
 RETURNING *;
 -- name: GetUser :one
@@ -13,3 +15,13 @@ SELECT * FROM users WHERE name = $1;
 DELETE FROM users;
 -- name: GetUsers :many
 SELECT * FROM users;
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1;
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1;
+-- name: GetUserByAPIKey :one
+SELECT * FROM users WHERE api_key_hash = $1;
+-- name: SetUserAPIKey :exec
+UPDATE users
+SET api_key_hash = $1, updated_at = $2
+WHERE id = $3;
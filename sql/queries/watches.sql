@@ -0,0 +1,51 @@
+-- name: CreateWatch :one
+INSERT INTO watches (id, created_at, user_id, field, match_type, pattern, channel, target, rate_limit, digest_interval)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7,
+  $8,
+  $9,
+  $10
+)
+RETURNING *;
+-- name: GetWatchesForUser :many
+SELECT * FROM watches
+WHERE user_id = $1
+ORDER BY created_at;
+-- name: GetWatchesForFeed :many
+SELECT watches.* FROM watches
+INNER JOIN feed_follows ON watches.user_id = feed_follows.user_id
+WHERE feed_follows.feed_id = $1
+ORDER BY watches.user_id, watches.created_at;
+-- name: GetWatchesWithPending :many
+SELECT * FROM watches
+WHERE id IN (SELECT watch_id FROM notifications WHERE sent_at IS NULL)
+ORDER BY created_at;
+-- name: SetWatchNotified :exec
+UPDATE watches SET last_notified_at = $2
+WHERE id = $1;
+-- name: DeleteWatch :exec
+DELETE FROM watches
+WHERE id = $1 AND user_id = $2;
+-- name: CreateNotification :exec
+INSERT INTO notifications (watch_id, post_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (watch_id, post_id) DO NOTHING;
+-- name: GetPendingNotifications :many
+SELECT posts.id, posts.title, posts.url, posts.published_at, feeds.name AS feed_name
+FROM notifications
+INNER JOIN posts ON notifications.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE notifications.watch_id = $1 AND notifications.sent_at IS NULL
+ORDER BY posts.published_at, posts.id;
+-- name: MarkNotificationsSent :exec
+UPDATE notifications SET sent_at = sqlc.arg(sent_at)
+WHERE watch_id = sqlc.arg(watch_id) AND post_id = ANY(sqlc.arg(post_ids)::uuid[]);
+-- name: CountWatchDeliveries :one
+SELECT count(DISTINCT sent_at) FROM notifications
+WHERE watch_id = $1 AND sent_at > $2;
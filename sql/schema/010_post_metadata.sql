@@ -0,0 +1,31 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN author TEXT,
+ADD COLUMN content TEXT,
+ADD COLUMN comments_url TEXT;
+
+CREATE TABLE post_categories (
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  PRIMARY KEY (post_id, name)
+);
+
+CREATE INDEX post_categories_name_idx ON post_categories (lower(name));
+
+CREATE TABLE post_enclosures (
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  url TEXT NOT NULL,
+  length BIGINT,
+  mime_type TEXT,
+  PRIMARY KEY (post_id, url)
+);
+
+
+-- +goose Down
+DROP TABLE post_enclosures;
+DROP TABLE post_categories;
+
+ALTER TABLE posts
+DROP COLUMN comments_url,
+DROP COLUMN content,
+DROP COLUMN author;
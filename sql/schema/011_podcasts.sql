@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN duration_seconds INTEGER,
+ADD COLUMN image_url TEXT;
+
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN image_url,
+DROP COLUMN duration_seconds;
@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE rules (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  field TEXT NOT NULL CHECK (field IN ('any', 'title', 'description', 'author', 'category')),
+  match_type TEXT NOT NULL CHECK (match_type IN ('keyword', 'regex')),
+  pattern TEXT NOT NULL,
+  action TEXT NOT NULL CHECK (action IN ('hide', 'read', 'tag')),
+  tag TEXT,
+  CHECK ((action = 'tag') = (tag IS NOT NULL))
+);
+
+CREATE INDEX rules_user_id_idx ON rules (user_id);
+
+CREATE TABLE post_tags (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  PRIMARY KEY (user_id, post_id, name)
+);
+
+CREATE INDEX post_tags_name_idx ON post_tags (user_id, lower(name));
+
+ALTER TABLE post_states
+ADD COLUMN hidden_at TIMESTAMP;
+
+
+-- +goose Down
+ALTER TABLE post_states
+DROP COLUMN hidden_at;
+
+DROP TABLE post_tags;
+DROP TABLE rules;
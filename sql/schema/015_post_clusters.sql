@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN cluster_id UUID;
+
+UPDATE posts SET cluster_id = id;
+
+ALTER TABLE posts
+ALTER COLUMN cluster_id SET NOT NULL;
+
+CREATE INDEX posts_cluster_id_idx ON posts (cluster_id);
+
+
+-- +goose Down
+DROP INDEX posts_cluster_id_idx;
+
+ALTER TABLE posts
+DROP COLUMN cluster_id;
@@ -0,0 +1,119 @@
+-- +goose Up
+-- the same rules as internal/urlcanon: lower case scheme and host, no
+-- default port, fragment, tracking parameters or trailing slash. urls that
+-- aren't http(s) are left alone.
+-- +goose StatementBegin
+CREATE FUNCTION canonical_url(url TEXT) RETURNS TEXT AS $$
+  SELECT CASE
+    WHEN parts.scheme NOT IN ('http', 'https') OR parts.host = '' THEN url
+    ELSE parts.scheme || '://' ||
+      CASE
+        WHEN parts.scheme = 'http' THEN regexp_replace(parts.host, ':80$', '')
+        ELSE regexp_replace(parts.host, ':443$', '')
+      END ||
+      coalesce(nullif(rtrim(parts.path, '/'), ''), '/') ||
+      coalesce('?' || nullif(array_to_string(array(
+        SELECT param
+        FROM unnest(string_to_array(parts.query, '&')) WITH ORDINALITY AS params(param, n)
+        WHERE param <> ''
+          AND lower(split_part(param, '=', 1)) !~ '^(utm_.*|fbclid|gclid|dclid|gbraid|wbraid|msclkid|mc_cid|mc_eid|_ga|_gl|igshid|yclid|twclid|oly_anon_id|oly_enc_id|vero_id)$'
+        ORDER BY n
+      ), '&'), ''), '')
+  END
+  FROM (
+    SELECT
+      coalesce(lower(substring(url FROM '^([A-Za-z][A-Za-z0-9+.-]*)://')), '') AS scheme,
+      coalesce(lower(substring(url FROM '^[A-Za-z][A-Za-z0-9+.-]*://([^/?#]*)')), '') AS host,
+      coalesce(substring(url FROM '^[A-Za-z][A-Za-z0-9+.-]*://[^/?#]*([^?#]*)'), '') AS path,
+      coalesce(substring(url FROM '^[^?#]*\?([^#]*)'), '') AS query
+  ) AS parts
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+-- duplicates share the canonical url under either scheme, the oldest row
+-- is kept and the others are merged into it
+CREATE TEMPORARY TABLE feed_merges AS
+SELECT id AS old_id, first_value(id) OVER same_url AS keep_id
+FROM feeds
+WINDOW same_url AS (
+  PARTITION BY regexp_replace(canonical_url(url), '^https?://', '')
+  ORDER BY created_at, id
+);
+DELETE FROM feed_merges WHERE old_id = keep_id;
+
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+SELECT gen_random_uuid(), feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_merges.keep_id
+FROM feed_follows
+JOIN feed_merges ON feed_merges.old_id = feed_follows.feed_id
+ON CONFLICT (user_id, feed_id) DO NOTHING;
+
+INSERT INTO feed_credentials (feed_id, created_at, updated_at, auth_type, username, query_param, secret)
+SELECT feed_merges.keep_id, feed_credentials.created_at, feed_credentials.updated_at,
+  feed_credentials.auth_type, feed_credentials.username, feed_credentials.query_param, feed_credentials.secret
+FROM feed_credentials
+JOIN feed_merges ON feed_merges.old_id = feed_credentials.feed_id
+ON CONFLICT (feed_id) DO NOTHING;
+
+UPDATE posts SET feed_id = feed_merges.keep_id
+FROM feed_merges
+WHERE posts.feed_id = feed_merges.old_id;
+
+DELETE FROM feeds WHERE id IN (SELECT old_id FROM feed_merges);
+
+UPDATE feeds SET url = canonical_url(url) WHERE url <> canonical_url(url);
+
+CREATE TEMPORARY TABLE post_merges AS
+SELECT id AS old_id, first_value(id) OVER same_url AS keep_id
+FROM posts
+WINDOW same_url AS (
+  PARTITION BY regexp_replace(canonical_url(url), '^https?://', '')
+  ORDER BY created_at, id
+);
+DELETE FROM post_merges WHERE old_id = keep_id;
+
+-- read, saved and hidden survive the merge if any of the duplicates had them
+INSERT INTO post_states (user_id, post_id, read_at, saved_at, hidden_at)
+SELECT post_states.user_id, post_merges.keep_id,
+  min(post_states.read_at), min(post_states.saved_at), min(post_states.hidden_at)
+FROM post_states
+JOIN post_merges ON post_merges.old_id = post_states.post_id
+GROUP BY post_states.user_id, post_merges.keep_id
+ON CONFLICT (user_id, post_id) DO UPDATE SET
+  read_at = coalesce(post_states.read_at, EXCLUDED.read_at),
+  saved_at = coalesce(post_states.saved_at, EXCLUDED.saved_at),
+  hidden_at = coalesce(post_states.hidden_at, EXCLUDED.hidden_at);
+
+INSERT INTO post_tags (user_id, post_id, name)
+SELECT post_tags.user_id, post_merges.keep_id, post_tags.name
+FROM post_tags
+JOIN post_merges ON post_merges.old_id = post_tags.post_id
+ON CONFLICT (user_id, post_id, name) DO NOTHING;
+
+INSERT INTO post_categories (post_id, name)
+SELECT post_merges.keep_id, post_categories.name
+FROM post_categories
+JOIN post_merges ON post_merges.old_id = post_categories.post_id
+ON CONFLICT (post_id, name) DO NOTHING;
+
+INSERT INTO post_enclosures (post_id, url, length, mime_type)
+SELECT post_merges.keep_id, post_enclosures.url, post_enclosures.length, post_enclosures.mime_type
+FROM post_enclosures
+JOIN post_merges ON post_merges.old_id = post_enclosures.post_id
+ON CONFLICT (post_id, url) DO NOTHING;
+
+UPDATE posts SET cluster_id = kept.cluster_id
+FROM post_merges
+JOIN posts AS kept ON kept.id = post_merges.keep_id
+WHERE posts.cluster_id = post_merges.old_id;
+
+DELETE FROM posts WHERE id IN (SELECT old_id FROM post_merges);
+
+UPDATE posts SET url = canonical_url(url) WHERE url <> canonical_url(url);
+
+DROP TABLE post_merges;
+DROP TABLE feed_merges;
+DROP FUNCTION canonical_url(TEXT);
+
+
+-- +goose Down
+-- merged duplicates and the original urls can't be restored
@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE watches (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  field TEXT NOT NULL CHECK (field IN ('any', 'title', 'description', 'author', 'category')),
+  match_type TEXT NOT NULL CHECK (match_type IN ('keyword', 'regex')),
+  pattern TEXT NOT NULL,
+  channel TEXT NOT NULL CHECK (channel IN ('desktop', 'webhook', 'email')),
+  target TEXT,
+  -- notifications per hour, the rest are sent together
+  rate_limit INTEGER NOT NULL CHECK (rate_limit > 0),
+  -- seconds between digests, NULL notifies right away
+  digest_interval INTEGER CHECK (digest_interval > 0),
+  last_notified_at TIMESTAMP,
+  CHECK ((channel = 'desktop') = (target IS NULL))
+);
+
+CREATE INDEX watches_user_id_idx ON watches (user_id);
+
+CREATE TABLE notifications (
+  watch_id UUID NOT NULL REFERENCES watches(id) ON DELETE CASCADE,
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL,
+  sent_at TIMESTAMP,
+  PRIMARY KEY (watch_id, post_id)
+);
+
+CREATE INDEX notifications_pending_idx ON notifications (watch_id) WHERE sent_at IS NULL;
+
+
+-- +goose Down
+DROP TABLE notifications;
+DROP TABLE watches;
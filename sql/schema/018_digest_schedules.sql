@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE digest_schedules (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- seconds between digests
+  send_interval INTEGER NOT NULL CHECK (send_interval > 0),
+  format TEXT NOT NULL CHECK (format IN ('text', 'markdown', 'html')),
+  group_by TEXT NOT NULL CHECK (group_by IN ('feed', 'category')),
+  channel TEXT NOT NULL CHECK (channel IN ('email', 'file')),
+  target TEXT NOT NULL,
+  last_sent_at TIMESTAMP
+);
+
+CREATE INDEX digest_schedules_user_id_idx ON digest_schedules (user_id);
+
+
+-- +goose Down
+DROP TABLE digest_schedules;
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/curator4/gator/internal/database"
+	"github.com/curator4/gator/internal/notify"
+	"github.com/curator4/gator/internal/rules"
+	"github.com/google/uuid"
+)
+
+// consts
+const watchesUsage = "usage: watch add [--field any|title|description|author|category] [--regex] [--limit n] [--digest duration]\n" +
+	"                 <pattern> desktop|webhook <url>|email <address>\n" +
+	"       watch list\n" +
+	"       watch remove <id>\n" +
+	"       watch test <id>"
+
+const (
+	channelDesktop = "desktop"
+	channelWebhook = "webhook"
+	channelEmail   = "email"
+)
+
+// notifications a watch sends per hour unless --limit says otherwise, the
+// posts over the limit wait and go out together
+const defaultWatchLimit = 10
+
+// digests can't come more often than this
+const minDigestInterval = time.Minute
+
+// structs
+type watchRow struct {
+	ID             uuid.UUID  `json:"id"`
+	ShortID        string     `json:"short_id"`
+	Field          string     `json:"field"`
+	MatchType      string     `json:"match_type"`
+	Pattern        string     `json:"pattern"`
+	Channel        string     `json:"channel"`
+	Target         string     `json:"target,omitempty"`
+	Limit          int32      `json:"limit"`
+	Digest         string     `json:"digest,omitempty"`
+	LastNotifiedAt *time.Time `json:"last_notified_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// compiledWatch is a stored watch, ready to match
+type compiledWatch struct {
+	database.Watch
+	matcher *rules.Matcher
+}
+
+// handlers
+func handlerWatch(s *state, cmd command, user database.User) error {
+	if len(cmd.args) == 0 {
+		return errors.New(watchesUsage)
+	}
+	args := cmd.args[1:]
+	switch cmd.args[0] {
+	case "add":
+		return watchAdd(s, args, user)
+	case "list":
+		if len(args) != 0 {
+			return errors.New("watch list takes no arguments")
+		}
+		return watchList(s, user)
+	case "remove":
+		if len(args) != 1 {
+			return errors.New("watch remove expects a watch id")
+		}
+		return watchRemove(s, args[0], user)
+	case "test":
+		if len(args) != 1 {
+			return errors.New("watch test expects a watch id")
+		}
+		return watchTest(s, args[0], user)
+	}
+	return errors.New(watchesUsage)
+}
+
+func watchAdd(s *state, args []string, user database.User) error {
+	params := database.CreateWatchParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		Field:     rules.FieldAny,
+		MatchType: rules.MatchKeyword,
+		RateLimit: defaultWatchLimit,
+	}
+
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "--field", "--limit", "--digest":
+			if i+1 >= len(args) {
+				return fmt.Errorf("%s expects a value", arg)
+			}
+			i++
+			value := args[i]
+			switch arg {
+			case "--field":
+				params.Field = value
+			case "--limit":
+				limit, err := strconv.Atoi(value)
+				if err != nil || limit < 1 {
+					return errors.New("--limit must be a positive number of notifications per hour")
+				}
+				params.RateLimit = int32(limit)
+			case "--digest":
+				interval, err := time.ParseDuration(value)
+				if err != nil || interval < minDigestInterval {
+					return fmt.Errorf("--digest expects a duration of at least %v, e.g. 1h", minDigestInterval)
+				}
+				params.DigestInterval = sql.NullInt32{Int32: int32(interval / time.Second), Valid: true}
+			}
+		case "--regex":
+			params.MatchType = rules.MatchRegex
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) < 2 {
+		return errors.New(watchesUsage)
+	}
+	params.Pattern = positional[0]
+	params.Channel = positional[1]
+
+	target := positional[2:]
+	switch params.Channel {
+	case channelDesktop:
+		if len(target) != 0 {
+			return errors.New("watch add ... desktop takes no target")
+		}
+	case channelWebhook:
+		if len(target) != 1 {
+			return errors.New("watch add ... webhook expects a url")
+		}
+		u, err := url.Parse(target[0])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("the webhook must be an http or https url")
+		}
+		params.Target = sql.NullString{String: target[0], Valid: true}
+	case channelEmail:
+		if len(target) != 1 {
+			return errors.New("watch add ... email expects an address")
+		}
+		address, err := mail.ParseAddress(target[0])
+		if err != nil {
+			return fmt.Errorf("invalid address %s: %w", target[0], err)
+		}
+		params.Target = sql.NullString{String: address.Address, Valid: true}
+	default:
+		return fmt.Errorf("unknown channel %q, use desktop, webhook or email", params.Channel)
+	}
+
+	if _, err := rules.NewMatcher(params.Field, params.MatchType, params.Pattern); err != nil {
+		return err
+	}
+
+	watch, err := s.db.CreateWatch(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("added watch %s: %s\n", shortID(watch.ID), describeWatch(watch))
+	if watch.Channel == channelEmail {
+		warnNoSMTP(s)
+	}
+	fmt.Println("`gator agg` sends the notifications, try it with: gator watch test " + shortID(watch.ID))
+	return nil
+}
+
+func watchList(s *state, user database.User) error {
+	stored, err := s.db.GetWatchesForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]watchRow, 0, len(stored))
+	for _, watch := range stored {
+		row := watchRow{
+			ID:        watch.ID,
+			ShortID:   shortID(watch.ID),
+			Field:     watch.Field,
+			MatchType: watch.MatchType,
+			Pattern:   watch.Pattern,
+			Channel:   watch.Channel,
+			Target:    watch.Target.String,
+			Limit:     watch.RateLimit,
+			CreatedAt: watch.CreatedAt,
+		}
+		if watch.DigestInterval.Valid {
+			row.Digest = digestInterval(watch).String()
+		}
+		if watch.LastNotifiedAt.Valid {
+			row.LastNotifiedAt = &watch.LastNotifiedAt.Time
+		}
+		rows = append(rows, row)
+	}
+
+	return printList(s, rows, listing[watchRow]{
+		columns: []string{"id", "field", "match_type", "pattern", "channel", "target", "limit", "digest", "last_notified_at", "created_at"},
+		row: func(r watchRow) []string {
+			last_notified := ""
+			if r.LastNotifiedAt != nil {
+				last_notified = r.LastNotifiedAt.Format(time.RFC3339)
+			}
+			return []string{r.ShortID, r.Field, r.MatchType, r.Pattern, r.Channel, r.Target, strconv.Itoa(int(r.Limit)), r.Digest, last_notified, r.CreatedAt.Format(time.RFC3339)}
+		},
+		text: func(rows []watchRow) {
+			if len(rows) == 0 {
+				fmt.Println("no watches, add one with: gator watch add <pattern> desktop|webhook <url>|email <address>")
+				return
+			}
+			for i, r := range rows {
+				fmt.Printf("%s  %s\n", r.ShortID, describeWatch(stored[i]))
+			}
+		},
+	})
+}
+
+func watchRemove(s *state, ref string, user database.User) error {
+	watch, err := findWatch(s, ref, user)
+	if err != nil {
+		return err
+	}
+	params := database.DeleteWatchParams{ID: watch.ID, UserID: user.ID}
+	if err := s.db.DeleteWatch(context.Background(), params); err != nil {
+		return err
+	}
+	fmt.Printf("removed watch %s: %s\n", shortID(watch.ID), describeWatch(watch))
+	return nil
+}
+
+// watchTest sends a made up post through a watch's channel, right away
+func watchTest(s *state, ref string, user database.User) error {
+	watch, err := findWatch(s, ref, user)
+	if err != nil {
+		return err
+	}
+	msg := notify.Message{
+		WatchID: watch.ID,
+		Watch:   rules.Describe(watch.Field, watch.MatchType, watch.Pattern),
+		Posts: []notify.Post{{
+			Title:       "gator test notification",
+			URL:         "https://github.com/curator4/gator",
+			Feed:        "gator",
+			PublishedAt: time.Now(),
+		}},
+	}
+	if err := sendNotification(s, watch, msg); err != nil {
+		return err
+	}
+	fmt.Printf("sent a test notification through %s\n", watch.Channel)
+	return nil
+}
+
+// helpers
+
+// findWatch looks up one of the user's watches by id or id prefix
+func findWatch(s *state, ref string, user database.User) (database.Watch, error) {
+	stored, err := s.db.GetWatchesForUser(context.Background(), user.ID)
+	if err != nil {
+		return database.Watch{}, err
+	}
+	return findByIDPrefix(stored, func(watch database.Watch) uuid.UUID { return watch.ID }, ref, "watch", "watch list")
+}
+
+// describeWatch says in one line what a watch looks for and how it
+// notifies, e.g. `title ~ "cve" → email me@example.com, at most 10 an hour`
+func describeWatch(watch database.Watch) string {
+	channel := watch.Channel
+	if watch.Target.Valid {
+		channel += " " + watch.Target.String
+	}
+	pace := fmt.Sprintf("at most %d an hour", watch.RateLimit)
+	if watch.DigestInterval.Valid {
+		pace = fmt.Sprintf("digest every %v", digestInterval(watch))
+	}
+	return fmt.Sprintf("%s → %s, %s", rules.Describe(watch.Field, watch.MatchType, watch.Pattern), channel, pace)
+}
+
+func digestInterval(watch database.Watch) time.Duration {
+	return time.Duration(watch.DigestInterval.Int32) * time.Second
+}
+
+// feedWatches loads the watches of everyone following a feed, by user.
+// Watches that no longer compile are skipped.
+func feedWatches(s *state, feedID uuid.UUID) (map[uuid.UUID][]compiledWatch, error) {
+	stored, err := s.db.GetWatchesForFeed(context.Background(), feedID)
+	if err != nil {
+		return nil, err
+	}
+	by_user := make(map[uuid.UUID][]compiledWatch)
+	for _, watch := range stored {
+		matcher, err := rules.NewMatcher(watch.Field, watch.MatchType, watch.Pattern)
+		if err != nil {
+			continue
+		}
+		by_user[watch.UserID] = append(by_user[watch.UserID], compiledWatch{Watch: watch, matcher: matcher})
+	}
+	return by_user, nil
+}
+
+// queueNotifications notes a new post for every watch of a user it
+// matches, deliverNotifications sends them
+func queueNotifications(s *state, user_watches []compiledWatch, post_id uuid.UUID, post rules.Post) error {
+	for _, watch := range user_watches {
+		if !watch.matcher.Match(post) {
+			continue
+		}
+		params := database.CreateNotificationParams{WatchID: watch.ID, PostID: post_id, CreatedAt: time.Now()}
+		if err := s.db.CreateNotification(context.Background(), params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliverNotifications sends what the watches have queued. Notifications
+// that fail stay queued and are tried again on the next round.
+func deliverNotifications(s *state) error {
+	watches, err := s.db.GetWatchesWithPending(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, watch := range watches {
+		if err := deliverWatch(s, watch); err != nil {
+			fmt.Printf("Error notifying watch %s: %v\n", shortID(watch.ID), err)
+		}
+	}
+	return nil
+}
+
+// deliverWatch sends the queued posts of a watch. A digest sends them all
+// at once when its interval is up. Otherwise each post is a notification
+// of its own, up to the hourly limit, and the last one the limit allows
+// carries all the posts that are left.
+func deliverWatch(s *state, watch database.Watch) error {
+	pending, err := s.db.GetPendingNotifications(context.Background(), watch.ID)
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	if watch.DigestInterval.Valid {
+		since := watch.CreatedAt
+		if watch.LastNotifiedAt.Valid {
+			since = watch.LastNotifiedAt.Time
+		}
+		if time.Since(since) < digestInterval(watch) {
+			return nil
+		}
+		return sendPending(s, watch, pending)
+	}
+
+	sent, err := s.db.CountWatchDeliveries(context.Background(), database.CountWatchDeliveriesParams{
+		WatchID: watch.ID,
+		SentAt:  sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	budget := int(watch.RateLimit) - int(sent)
+	for i := 0; i < len(pending) && budget > 0; i++ {
+		batch := pending[i : i+1]
+		if budget == 1 {
+			batch = pending[i:]
+		}
+		if err := sendPending(s, watch, batch); err != nil {
+			return err
+		}
+		budget--
+		i += len(batch) - 1
+	}
+	return nil
+}
+
+// sendPending sends queued posts as one notification and marks them sent
+func sendPending(s *state, watch database.Watch, pending []database.GetPendingNotificationsRow) error {
+	msg := notify.Message{
+		WatchID: watch.ID,
+		Watch:   rules.Describe(watch.Field, watch.MatchType, watch.Pattern),
+		Posts:   make([]notify.Post, 0, len(pending)),
+	}
+	post_ids := make([]uuid.UUID, 0, len(pending))
+	for _, post := range pending {
+		msg.Posts = append(msg.Posts, notify.Post{
+			ID:          post.ID,
+			Title:       post.Title,
+			URL:         post.Url,
+			Feed:        post.FeedName,
+			PublishedAt: post.PublishedAt,
+		})
+		post_ids = append(post_ids, post.ID)
+	}
+	if err := sendNotification(s, watch, msg); err != nil {
+		return err
+	}
+
+	sent_at := sql.NullTime{Time: time.Now(), Valid: true}
+	params := database.MarkNotificationsSentParams{SentAt: sent_at, WatchID: watch.ID, PostIds: post_ids}
+	if err := s.db.MarkNotificationsSent(context.Background(), params); err != nil {
+		return err
+	}
+	return s.db.SetWatchNotified(context.Background(), database.SetWatchNotifiedParams{ID: watch.ID, LastNotifiedAt: sent_at})
+}
+
+func sendNotification(s *state, watch database.Watch, msg notify.Message) error {
+	switch watch.Channel {
+	case channelDesktop:
+		return notify.Desktop(context.Background(), msg)
+	case channelWebhook:
+		return notify.Webhook(context.Background(), watch.Target.String, msg)
+	case channelEmail:
+		return mailer(s).Email(watch.Target.String, msg)
+	}
+	return fmt.Errorf("unknown channel %q", watch.Channel)
+}
+
+// warnNoSMTP tells when email was picked before a mail server is configured
+func warnNoSMTP(s *state) {
+	if s.cfg.SMTP == nil {
+		fmt.Println("no smtp server is configured yet, add one under smtp in ~/.gatorconfig.json")
+	}
+}
+
+// mailer is the smtp server of the config, without one sending fails and
+// says what to set
+func mailer(s *state) notify.Mailer {
+	if s.cfg.SMTP == nil {
+		return notify.Mailer{}
+	}
+	return notify.Mailer{
+		Host:     s.cfg.SMTP.Host,
+		Port:     s.cfg.SMTP.Port,
+		Username: s.cfg.SMTP.Username,
+		Password: s.cfg.SMTP.Password,
+		From:     s.cfg.SMTP.From,
+	}
+}